@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	strWorkdays  = "workdays"
+	strWorkhours = "workhours"
+)
+
+// workCalendar defines the business days and the working hours. The days and the hours are in the location of
+// the evaluation, e.g. set with -tz.
+type workCalendar struct {
+	// weekdays are the working days of the week, indexed by time.Weekday.
+	weekdays [7]bool
+	// start and end are the working hours, in minutes since midnight.
+	start int
+	end   int
+	// holidays are the dates, formatted like 2006-01-02, that are not business days.
+	holidays map[string]bool
+}
+
+// calendar is the calendar of the business days, set from the command line flags.
+var calendar = workCalendar{
+	weekdays: [7]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true},
+	start:    9 * 60,
+	end:      17 * 60,
+	holidays: map[string]bool{},
+}
+
+// maxCalendarDays is the limit of the days the business arithmetic looks through, so a calendar without any business
+// day does not loop forever.
+const maxCalendarDays = 100 * 366
+
+// businessNode is the period in business days, e.g. `3bd`, or in working hours, e.g. `4bh`.
+type businessNode struct {
+	days  int
+	hours time.Duration
+	cur   p.Cursor
+}
+
+func (n businessNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n businessNode) String() string {
+	if n.hours != 0 {
+		return fmt.Sprintf("%gbh", n.hours.Hours())
+	}
+	return fmt.Sprintf("%dbd", n.days)
+}
+
+func (n businessNode) neg() businessNode {
+	n.days, n.hours = -n.days, -n.hours
+	return n
+}
+
+var businessPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(bd|bh|business\s+days?|business\s+hours?|working\s+days?|working\s+hours?)\b`)
+
+// businessPeriod parses the periods in business days, e.g. `3bd`, `3 business days` or `3 working days`, and in
+// working hours, e.g. `4bh`, `4 business hours` or `4 working hours`.
+var businessPeriod = p.Map(
+	p.Regex(businessPattern.String()),
+	func(node p.Node) (p.Node, error) {
+		literal := node.(p.LiteralNode)
+		m := businessPattern.FindStringSubmatch(literal.Literal)
+		value, _ := strconv.ParseFloat(m[1], 64)
+		if m[2] == "bd" || strings.HasSuffix(strings.TrimSuffix(m[2], "s"), "day") {
+			if value != math.Trunc(value) {
+				return nil, cursorError{err: fmt.Errorf("the number of business days must be whole, not %s", m[1]), cur: literal.Cursor()}
+			}
+			return businessNode{days: int(value), cur: literal.Cursor()}, nil
+		}
+		return businessNode{hours: time.Duration(value * float64(time.Hour)), cur: literal.Cursor()}, nil
+	},
+)
+
+// workNode is the number of business days, e.g. `workdays(today .. today + 1mo)`, or the working hours, e.g.
+// `workhours(start .. end)`, in the interval.
+type workNode struct {
+	name string
+	node p.Node
+	cur  p.Cursor
+}
+
+func (n workNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n workNode) String() string {
+	return fmt.Sprintf("%s(%s)", n.name, n.node)
+}
+
+func buildWork(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	if seq.Len() != 3 {
+		return nil, cursorError{err: fmt.Errorf("missing closing parenthesis"), cur: seq.Cursor()}
+	}
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(seq.Nodes[0].(p.LiteralNode).Literal), "("))
+	return workNode{name: name, node: seq.Nodes[1], cur: seq.Cursor()}, nil
+}
+
+// evalWork returns the number of the business days in the interval, as a number, or the working hours, as a period.
+func (e evaluator) evalWork(n workNode) (p.Node, error) {
+	operand, err := e.evalNode(n.node)
+	if err != nil {
+		return nil, err
+	}
+	interval, ok := e.toValue(operand, false).(intervalNode)
+	if !ok {
+		return nil, cursorError{err: fmt.Errorf("cannot count %s of %s", n.name, withArticle(typeName(operand))), cur: n.Cursor()}
+	}
+	if n.name == strWorkdays {
+		days, err := calendar.countDays(interval.start, interval.end, e.loc)
+		if err != nil {
+			return nil, cursorError{err: err, cur: n.Cursor()}
+		}
+		return scalarNode{value: float64(days), cur: n.Cursor()}, nil
+	}
+	hours, err := calendar.countHours(interval.start, interval.end, e.loc)
+	if err != nil {
+		return nil, cursorError{err: err, cur: n.Cursor()}
+	}
+	return p.PeriodNode{Duration: hours, Cur: n.Cursor()}, nil
+}
+
+// isWorkday reports if the date in the location is a business day.
+func (c workCalendar) isWorkday(day time.Time) bool {
+	return c.weekdays[day.Weekday()] && !c.holidays[day.Format("2006-01-02")]
+}
+
+// hours returns the working hours of the day, as the wall clock in its location.
+func (c workCalendar) hours(day time.Time) (time.Time, time.Time) {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, c.start, 0, 0, day.Location()), time.Date(y, m, d, 0, c.end, 0, 0, day.Location())
+}
+
+// midnight returns the start of the day of the timestamp in the location.
+func midnight(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// add moves the timestamp by the business period.
+func (c workCalendar) add(t time.Time, period businessNode, loc *time.Location) (time.Time, error) {
+	if period.hours != 0 {
+		return c.addHours(t, period.hours, loc)
+	}
+	return c.addDays(t, period.days, loc)
+}
+
+// addDays moves the timestamp by the number of the business days, keeping the time of the day. The days before
+// the first business day are skipped, e.g. Saturday + 1bd is Monday.
+func (c workCalendar) addDays(t time.Time, days int, loc *time.Location) (time.Time, error) {
+	step := 1
+	if days < 0 {
+		step, days = -1, -days
+	}
+	day := midnight(t, loc)
+	for i := 0; days > 0; i++ {
+		if i >= maxCalendarDays {
+			return t, fmt.Errorf("there are no business days in %d days", maxCalendarDays)
+		}
+		day = day.AddDate(0, 0, step)
+		if c.isWorkday(day) {
+			days--
+		}
+	}
+	local := t.In(loc)
+	y, m, d := day.Date()
+	return time.Date(y, m, d, local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), loc), nil
+}
+
+// addHours moves the timestamp by the working time. The time outside of the working hours is skipped, e.g. Friday
+// 16:00 + 2bh is Monday 10:00 with the working hours 9:00-17:00.
+func (c workCalendar) addHours(t time.Time, hours time.Duration, loc *time.Location) (time.Time, error) {
+	if hours == 0 {
+		return t, nil
+	}
+	day := midnight(t, loc)
+	for i := 0; i < maxCalendarDays; i++ {
+		if c.isWorkday(day) {
+			start, end := c.hours(day)
+			// left is the working time of the day after the timestamp, or before it when moving backwards.
+			var left time.Duration
+			if hours > 0 {
+				from := later(t, start)
+				if left = end.Sub(from); hours <= left {
+					return from.Add(hours), nil
+				}
+			} else {
+				to := earlier(t, end)
+				if left = to.Sub(start); -hours <= left {
+					return to.Add(hours), nil
+				}
+			}
+			if left > 0 {
+				if hours > 0 {
+					hours -= left
+				} else {
+					hours += left
+				}
+			}
+		}
+		if hours > 0 {
+			day = day.AddDate(0, 0, 1)
+			t = day
+		} else {
+			t = day
+			day = day.AddDate(0, 0, -1)
+		}
+	}
+	return t, fmt.Errorf("there are no working hours in %d days", maxCalendarDays)
+}
+
+// countDays returns the number of the business days after the day of the start, up to the day of the end, so that
+// workdays(t .. t + 3bd) is 3.
+func (c workCalendar) countDays(start, end time.Time, loc *time.Location) (int, error) {
+	days := 0
+	last := midnight(end, loc)
+	for day, i := midnight(start, loc).AddDate(0, 0, 1), 0; !day.After(last); day, i = day.AddDate(0, 0, 1), i+1 {
+		if i >= maxCalendarDays {
+			return 0, fmt.Errorf("cannot count business days in more than %d days", maxCalendarDays)
+		}
+		if c.isWorkday(day) {
+			days++
+		}
+	}
+	return days, nil
+}
+
+// countHours returns the working time between the timestamps.
+func (c workCalendar) countHours(start, end time.Time, loc *time.Location) (time.Duration, error) {
+	total := time.Duration(0)
+	last := midnight(end, loc)
+	for day, i := midnight(start, loc), 0; !day.After(last); day, i = day.AddDate(0, 0, 1), i+1 {
+		if i >= maxCalendarDays {
+			return 0, fmt.Errorf("cannot count working hours in more than %d days", maxCalendarDays)
+		}
+		if !c.isWorkday(day) {
+			continue
+		}
+		from, to := c.hours(day)
+		if overlap := earlier(to, end).Sub(later(from, start)); overlap > 0 {
+			total += overlap
+		}
+	}
+	return total, nil
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWorkweek parses the working days of the week, e.g. "Mon-Fri", "Sun-Thu" or "Mon,Tue,Thu-Fri".
+func parseWorkweek(s string) ([7]bool, error) {
+	days := [7]bool{}
+	invalid := fmt.Errorf("invalid workweek %q, expected days like Mon-Fri or Mon,Wed,Fri", s)
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		from, ok := weekdayNames[strings.ToLower(bounds[0])]
+		if !ok {
+			return days, invalid
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, ok = weekdayNames[strings.ToLower(bounds[1])]; !ok {
+				return days, invalid
+			}
+		}
+		// The ranges can wrap around the end of the week, e.g. Fri-Mon.
+		for d := from; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseWorkhours parses the working hours, e.g. "09:00-17:00", to the minutes since midnight.
+func parseWorkhours(s string) (int, int, error) {
+	invalid := fmt.Errorf("invalid working hours %q, expected hours like 09:00-17:00", s)
+	bounds := strings.SplitN(s, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, invalid
+	}
+	minutes := [2]int{}
+	for i, b := range bounds {
+		t, err := time.Parse("15:04", strings.TrimSpace(b))
+		if err != nil {
+			// 24:00 is the end of the day.
+			if strings.TrimSpace(b) != "24:00" {
+				return 0, 0, invalid
+			}
+			t = time.Date(0, 1, 2, 0, 0, 0, 0, time.UTC)
+		}
+		minutes[i] = int(t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)).Minutes())
+	}
+	if minutes[0] >= minutes[1] {
+		return 0, 0, fmt.Errorf("the working hours %q end before they start", s)
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// loadHolidays reads the holidays from the file, either an iCalendar (ICS) file or a list of the dates like
+// 2023-12-25, one per line. The lines starting with # are comments, and the text after the date is ignored, so it
+// can be the name of the holiday.
+func loadHolidays(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseHolidays(f)
+}
+
+func parseHolidays(r io.Reader) (map[string]bool, error) {
+	holidays := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	// ics is true in the iCalendar file, which starts with BEGIN:VCALENDAR.
+	ics := false
+	var start, end time.Time
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 && strings.EqualFold(line, "BEGIN:VCALENDAR") {
+			ics = true
+		}
+		if ics {
+			name, value, _ := strings.Cut(line, ":")
+			key, _, _ := strings.Cut(name, ";")
+			switch strings.ToUpper(key) {
+			case "BEGIN":
+				start, end = time.Time{}, time.Time{}
+			case "DTSTART", "DTEND":
+				// The date is the first 8 digits, also of the date-time values like 20231225T000000Z.
+				if len(value) < 8 {
+					return nil, fmt.Errorf("line %d: invalid date %q", lineNum, value)
+				}
+				date, err := time.Parse("20060102", value[:8])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid date %q", lineNum, value)
+				}
+				if strings.EqualFold(key, "DTSTART") {
+					start = date
+				} else {
+					end = date
+				}
+			case "END":
+				if !strings.EqualFold(value, "VEVENT") || start.IsZero() {
+					continue
+				}
+				// The end date of the all-day event is exclusive.
+				holidays[start.Format("2006-01-02")] = true
+				for day := start.AddDate(0, 0, 1); day.Before(end); day = day.AddDate(0, 0, 1) {
+					holidays[day.Format("2006-01-02")] = true
+				}
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", strings.Fields(line)[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q, expected a date like 2023-12-25", lineNum, strings.Fields(line)[0])
+		}
+		holidays[date.Format("2006-01-02")] = true
+	}
+	return holidays, scanner.Err()
+}
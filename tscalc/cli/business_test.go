@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessArithmetic(t *testing.T) {
+	// Friday.
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 27, 16, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now + 1bd", "2023-10-30T16:00:00+00:00"},
+		{"now + 3 business days", "2023-11-01T16:00:00+00:00"},
+		{"now - 5bd", "2023-10-20T16:00:00+00:00"},
+		{"2023-10-28T12:00:00Z + 1bd", "2023-10-30T12:00:00+00:00"},
+		{"now + 2bh", "2023-10-30T10:00:00+00:00"},
+		{"now + 0.5bh", "2023-10-27T16:30:00+00:00"},
+		{"now + 4 working hours", "2023-10-30T12:00:00+00:00"},
+		{"2023-10-30T10:00:00Z - 2bh", "2023-10-27T16:00:00+00:00"},
+		{"2023-10-28T12:00:00Z + 1bh", "2023-10-30T10:00:00+00:00"},
+		{"2023-10-27T07:00:00Z + 8bh", "2023-10-27T17:00:00+00:00"},
+		{"-2bd + 2023-10-30T10:00:00Z", "2023-10-26T10:00:00+00:00"},
+		{"now + 2bh in Europe/Warsaw", "2023-10-30T11:00:00+01:00"},
+		{"workdays(now .. now + 3bd)", "3"},
+		{"workdays(now .. 2023-11-03T10:00:00Z)", "5"},
+		{"workhours(now .. 2023-10-30T10:00:00Z)", "2h0m0s"},
+		{"workhours(2023-10-23T00:00:00Z .. now)", "39h0m0s"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestBusinessErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now + 1.5bd", "the number of business days must be whole, not 1.5"},
+		{"1bd + 1h", "cannot add a business period and a period"},
+		{"now - 1bd - now + 1bd", "cannot add a period and a business period"},
+		{"workdays(1h)", "cannot count workdays of a period"},
+		{"workhours(now .. now", "missing closing parenthesis"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestBusinessCalendar(t *testing.T) {
+	saved := calendar
+	defer func() { calendar = saved }()
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 27, 16, 0, 0, 0, time.UTC)
+	}
+
+	var err error
+	calendar.weekdays, err = parseWorkweek("Sun-Thu")
+	assert.NoError(t, err)
+	calendar.start, calendar.end, err = parseWorkhours("08:00-16:00")
+	assert.NoError(t, err)
+	actual, err := handleLine("now + 2bh")
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-10-29T10:00:00+00:00", actual)
+
+	calendar = saved
+	calendar.holidays, err = parseHolidays(strings.NewReader("# Bridge days\n2023-10-30 Monday\n2023-10-31\n"))
+	assert.NoError(t, err)
+	actual, err = handleLine("now + 1bd")
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-11-01T16:00:00+00:00", actual)
+
+	calendar.weekdays = [7]bool{}
+	_, err = handleLine("now + 1bd")
+	assert.EqualError(t, err, "there are no business days in 36600 days")
+}
+
+func TestParseWorkweek(t *testing.T) {
+	days, err := parseWorkweek("Mon-Wed,fri")
+	assert.NoError(t, err)
+	assert.Equal(t, [7]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Friday: true}, days)
+	days, err = parseWorkweek("Fri-Mon")
+	assert.NoError(t, err)
+	assert.Equal(t, [7]bool{time.Friday: true, time.Saturday: true, time.Sunday: true, time.Monday: true}, days)
+	_, err = parseWorkweek("Monday-Friday")
+	assert.EqualError(t, err, `invalid workweek "Monday-Friday", expected days like Mon-Fri or Mon,Wed,Fri`)
+}
+
+func TestParseWorkhours(t *testing.T) {
+	start, end, err := parseWorkhours("08:30-24:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 8*60+30, start)
+	assert.Equal(t, 24*60, end)
+	_, _, err = parseWorkhours("17:00-09:00")
+	assert.EqualError(t, err, `the working hours "17:00-09:00" end before they start`)
+	_, _, err = parseWorkhours("9-17")
+	assert.EqualError(t, err, `invalid working hours "9-17", expected hours like 09:00-17:00`)
+}
+
+func TestParseHolidays(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20231224",
+		"DTEND;VALUE=DATE:20231227",
+		"SUMMARY:Christmas",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20240101T000000Z",
+		"SUMMARY:New Year",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	holidays, err := parseHolidays(strings.NewReader(ics))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"2023-12-24": true, "2023-12-25": true, "2023-12-26": true, "2024-01-01": true}, holidays)
+
+	_, err = parseHolidays(strings.NewReader("2023-12-25\n25.12.2023\n"))
+	assert.EqualError(t, err, `line 2: invalid date "25.12.2023", expected a date like 2023-12-25`)
+}
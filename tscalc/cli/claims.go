@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	p "lib/tscalc/parse"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jwtClaims are the time claims of the JWTs. The other claims, notbefore and notafter, are of the certificates.
+var jwtClaims = map[string]bool{"exp": true, "iat": true, "nbf": true}
+
+// claimNode is the time from the JWT, e.g. `exp(eyJhbGciOi...)`, or from the certificate file, e.g.
+// `notafter(cert.pem)`.
+type claimNode struct {
+	name string
+	// source is the JWT or the path of the certificate.
+	source string
+	cur    p.Cursor
+	// sourceCur points at the source, for the errors of decoding it.
+	sourceCur p.Cursor
+}
+
+func (n claimNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n claimNode) String() string {
+	return fmt.Sprintf("%s(%s)", n.name, n.source)
+}
+
+// claim parses the claims: the name, the JWT or the path, optionally quoted, and the closing parenthesis.
+var claim = p.Map(
+	p.Sequence(
+		p.RegexGroup(`(exp|iat|nbf|notbefore|notafter)\(\s*`),
+		p.Regex(`"[^"]*"|'[^']*'|[^\s()]+`),
+		p.Regex(`\s*\)`),
+	),
+	func(node p.Node) (p.Node, error) {
+		seq := node.(p.SequenceNode)
+		name := seq.Nodes[0].(p.LiteralNode)
+		if seq.Len() != 3 {
+			expected := map[bool]string{true: "JWT", false: "certificate file"}[jwtClaims[name.Literal]]
+			if seq.Len() == 2 {
+				expected = "closing parenthesis"
+			}
+			return nil, cursorError{err: fmt.Errorf("expected %s after %s(", expected, name.Literal), cur: name.Cursor()}
+		}
+		source := seq.Nodes[1].(p.LiteralNode)
+		text := source.Literal
+		if unquoted, err := strconv.Unquote(text); err == nil {
+			text = unquoted
+		} else if strings.HasPrefix(text, "'") {
+			text = strings.Trim(text, "'")
+		}
+		return claimNode{name: name.Literal, source: text, cur: name.Cursor(), sourceCur: source.Cursor()}, nil
+	},
+)
+
+// evalClaim decodes the JWT, without verifying its signature, or reads the certificate.
+func (e evaluator) evalClaim(n claimNode) (p.Node, error) {
+	var t time.Time
+	var err error
+	if jwtClaims[n.name] {
+		t, err = jwtClaim(n.source, n.name)
+	} else {
+		t, err = certClaim(n.source, n.name)
+	}
+	if err != nil {
+		return nil, cursorError{err: err, cur: n.sourceCur}
+	}
+	return p.IsoTimeNode{Time: t, Cur: n.cur}, nil
+}
+
+// jwtClaim returns the time claim of the JWT. The claims are the seconds since the epoch, possibly fractional.
+func jwtClaim(token, name string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid JWT, expected 3 parts separated by dots, not %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	claims := map[string]any{}
+	if err := decoder.Decode(&claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, fmt.Errorf("the JWT has no %s claim", name)
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("the %s claim of the JWT is not a number: %v", name, value)
+	}
+	sec, err := number.Float64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the %s claim of the JWT is not a number: %v", name, value)
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(math.Round(frac*1e9))), nil
+}
+
+// certClaim returns the start or the end of the validity of the certificate in the file. The file is PEM, where
+// the first certificate is used, or DER.
+func certClaim(path, name string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	der := data
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			der = block.Bytes
+			break
+		}
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read the certificate from %s: %w", path, err)
+	}
+	if name == "notbefore" {
+		return cert.NotBefore, nil
+	}
+	return cert.NotAfter, nil
+}
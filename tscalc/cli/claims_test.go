@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testJwt(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestJwtClaims(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 18, 49, 24, 0, time.UTC)
+	}
+	jwt := testJwt(`{"sub":"1234567890","iat":1698603564,"nbf":1698603564,"exp":1698607164.5}`)
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{fmt.Sprintf("exp(%s)", jwt), "2023-10-29T19:19:24.5+00:00"},
+		{fmt.Sprintf("exp(%s) - now", jwt), "30m0.5s"},
+		{fmt.Sprintf("exp( '%s' ) - iat(%s)", jwt, jwt), "1h0m0.5s"},
+		{fmt.Sprintf("now in (nbf(%s) .. exp(%s))", jwt, jwt), "true"},
+		{fmt.Sprintf("iat(%s) in Europe/Warsaw", jwt), "2023-10-29T19:19:24+01:00"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCertClaims(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2023, 12, 30, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	pemPath, derPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert.der")
+	assert.NoError(t, os.WriteFile(pemPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(derPath, der, 0o600))
+
+	actual, err := handleLine(fmt.Sprintf("notafter(%s) - notbefore(%s)", pemPath, pemPath))
+	assert.NoError(t, err)
+	assert.Equal(t, "2160h0m0s", actual)
+	actual, err = handleLine(fmt.Sprintf(`notafter("%s")`, derPath))
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-12-30T00:00:00+00:00", actual)
+}
+
+func TestClaimErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"exp(abc)", "invalid JWT, expected 3 parts separated by dots, not 1"},
+		{"exp(a.b!.c)", "invalid JWT payload: illegal base64 data at input byte 1"},
+		{fmt.Sprintf("nbf(%s)", testJwt(`{"exp":1}`)), "the JWT has no nbf claim"},
+		{fmt.Sprintf("exp(%s)", testJwt(`{"exp":"soon"}`)), "the exp claim of the JWT is not a number: soon"},
+		{"notafter(" + missing + ")", fmt.Sprintf("open %s: no such file or directory", missing)},
+		{"notafter(", "expected certificate file after notafter("},
+		{"exp(abc", "expected closing parenthesis after exp("},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
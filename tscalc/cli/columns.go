@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"strconv"
+	"strings"
+)
+
+// fieldNode is the reference to the column of the row, e.g. `$3`. The columns are numbered from 1.
+type fieldNode struct {
+	column int
+	cur    p.Cursor
+}
+
+func (n fieldNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n fieldNode) String() string {
+	return fmt.Sprintf("$%d", n.column)
+}
+
+// field parses the references to the columns.
+var field = p.Map(
+	p.RegexGroup(`\$(\d+)`),
+	func(node p.Node) (p.Node, error) {
+		literal := node.(p.LiteralNode)
+		column, err := strconv.Atoi(literal.Literal)
+		if err != nil || column < 1 {
+			return nil, cursorError{err: fmt.Errorf("invalid column $%s, the columns are numbered from 1", literal.Literal), cur: literal.Cursor()}
+		}
+		return fieldNode{column: column, cur: literal.Cursor()}, nil
+	},
+)
+
+// fieldError is the error of the value in the column.
+type fieldError struct {
+	column int
+	err    error
+}
+
+func (e fieldError) Error() string {
+	return fmt.Sprintf("column %d: %v", e.column, e.err)
+}
+
+// fieldValue parses the value of the column in any of the supported formats.
+func (e evaluator) fieldValue(n fieldNode) (p.Node, error) {
+	if e.row == nil {
+		return nil, cursorError{err: fmt.Errorf("columns like %s can be used only with -e", n), cur: n.Cursor()}
+	}
+	if n.column > len(e.row) {
+		return nil, fieldError{column: n.column, err: fmt.Errorf("the row has only %d columns", len(e.row))}
+	}
+	text := strings.TrimSpace(e.row[n.column-1])
+	node, rest, err := e.values.Parse(p.NewCursor(text))
+	if err != nil {
+		return nil, fieldError{column: n.column, err: err}
+	}
+	if node == nil || !rest.Ended() {
+		return nil, fieldError{column: n.column, err: fmt.Errorf("cannot parse %q", text)}
+	}
+	return node, nil
+}
+
+// columnar evaluates the expression for every row of the delimited input, like CSV or TSV.
+type columnar struct {
+	expr  string
+	delim rune
+	// header is true if the first row are the names of the columns. The name of the result column is
+	// the expression.
+	header bool
+	// only prints the result instead of appending it to the row.
+	only bool
+}
+
+// parseDelimiter returns the delimiter given as a character or an escape sequence, e.g. `\t`.
+func parseDelimiter(s string) (rune, error) {
+	unquoted, err := strconv.Unquote(`"` + s + `"`)
+	if err != nil || len([]rune(unquoted)) != 1 {
+		return 0, fmt.Errorf("invalid delimiter %q, expected a single character like , or \\t", s)
+	}
+	return []rune(unquoted)[0], nil
+}
+
+// run evaluates the expression for the rows of the input and writes the rows with the results to the output.
+// The errors are reported to errOut with the row and the column, and the result of such row is empty. It returns
+// the number of the rows that failed.
+func (c columnar) run(in io.Reader, out, errOut io.Writer) (int, error) {
+	root, opts, err := parseLine(c.expr, defaultOptions, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := root.(assignNode); ok {
+		return 0, fmt.Errorf("the expression of -e cannot be an assignment")
+	}
+	values := valueParser(opts)
+
+	reader := csv.NewReader(in)
+	reader.Comma = c.delim
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	writer := csv.NewWriter(out)
+	writer.Comma = c.delim
+
+	failed := 0
+	for rowNum := 1; ; rowNum++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return failed, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			fmt.Fprintf(errOut, "row %d: %v\n", rowNum, err)
+			failed++
+			continue
+		}
+		if err != nil {
+			return failed, err
+		}
+
+		var res string
+		if c.header && rowNum == 1 {
+			res = c.expr
+		} else {
+			e := evaluator{now: nowFunc(), loc: opts.loc, row: row, values: values}
+			result, err := e.eval(root)
+			if err == nil {
+				res, err = formatResult(result, opts)
+			}
+			var ferr fieldError
+			switch {
+			case errors.As(err, &ferr):
+				fmt.Fprintf(errOut, "row %d, column %d: %v\n", rowNum, ferr.column, ferr.err)
+				failed++
+			case err != nil:
+				fmt.Fprintf(errOut, "row %d: %v\n", rowNum, err)
+				failed++
+			}
+		}
+		if c.only {
+			row = []string{res}
+		} else {
+			row = append(row, res)
+		}
+		// The rows are flushed one by one, so they are in order with the errors on the terminal.
+		if err := writer.Write(row); err != nil {
+			return failed, err
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return failed, err
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnar(t *testing.T) {
+	input := strings.Join([]string{
+		"id\tstart\tend",
+		"1\t2023-10-29T19:40:09Z\t2023-10-29T19:40:10.5Z",
+		"2\t1698603564000\t1698603565250",
+		"3\t2023-10-29 19:40:09+00\t[29/Oct/2023:19:40:19 +0000]",
+	}, "\n")
+	for _, tc := range []struct {
+		name     string
+		c        columnar
+		expected []string
+	}{
+		{"append with header", columnar{expr: "$3 - $2", delim: '\t', header: true}, []string{
+			"id\tstart\tend\t$3 - $2",
+			"1\t2023-10-29T19:40:09Z\t2023-10-29T19:40:10.5Z\t1.5s",
+			"2\t1698603564000\t1698603565250\t1.25s",
+			"3\t2023-10-29 19:40:09+00\t[29/Oct/2023:19:40:19 +0000]\t10s",
+		}},
+		{"only with suffix", columnar{expr: "$3 - $2 in ms", delim: '\t', header: true, only: true}, []string{
+			"$3 - $2 in ms",
+			"1500.00ms",
+			"1250.00ms",
+			"10000.00ms",
+		}},
+		{"single column", columnar{expr: "$2 as epoch", delim: '\t', header: true, only: true}, []string{
+			"$2 as epoch",
+			"1698608409",
+			"1698603564",
+			"1698608409",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, errOut := bytes.Buffer{}, bytes.Buffer{}
+			failed, err := tc.c.run(strings.NewReader(input), &out, &errOut)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, failed)
+			assert.Equal(t, strings.Join(tc.expected, "\n")+"\n", out.String())
+			assert.Empty(t, errOut.String())
+		})
+	}
+}
+
+func TestColumnarCsv(t *testing.T) {
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	c := columnar{expr: "$2 + 1d as date", delim: ','}
+	failed, err := c.run(strings.NewReader("\"x, y\",1698603564\n"), &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, "\"x, y\",1698603564,2023-10-30\n", out.String())
+}
+
+func TestColumnarErrors(t *testing.T) {
+	input := strings.Join([]string{
+		"1\tfoo\t1698603565",
+		"2\t1698603565",
+		"3\t1698603565\t1h",
+		"4\t1698603564\t1698603565",
+	}, "\n")
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	c := columnar{expr: "$3 - $2", delim: '\t'}
+	failed, err := c.run(strings.NewReader(input), &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, failed)
+	assert.Equal(t, "1\tfoo\t1698603565\t\n2\t1698603565\t\n3\t1698603565\t1h\t\n4\t1698603564\t1698603565\t1s\n", out.String())
+	assert.Equal(t, `row 1, column 2: cannot parse "foo"
+row 2, column 3: the row has only 2 columns
+row 3: cannot subtract a timestamp from a period
+`, errOut.String())
+}
+
+func TestColumnarInvalidExpression(t *testing.T) {
+	c := columnar{expr: "$1 +", delim: '\t'}
+	_, err := c.run(strings.NewReader("1\n"), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.EqualError(t, err, "expected operand after +")
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected rune
+	}{
+		{`\t`, '\t'},
+		{",", ','},
+		{";", ';'},
+		{"|", '|'},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := parseDelimiter(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, d)
+		})
+	}
+	_, err := parseDelimiter("ab")
+	assert.Error(t, err)
+}
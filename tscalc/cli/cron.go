@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronHorizon is how far the cron schedule is searched, so the schedules that never fire, like `0 0 30 2 *`, end
+// with an error. It is more than 4 years, so the schedules of February 29 are found.
+const cronHorizon = 5
+
+// cronMacros are the shortcuts of the common schedules.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronBits is the set of the values of a field, e.g. bit 5 of the minutes is the 5th minute.
+type cronBits uint64
+
+func (b cronBits) has(v int) bool {
+	return b&(1<<uint(v)) != 0
+}
+
+// cronField describes the range of the values of a field and their names.
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var (
+	cronSecond  = cronField{name: "second", min: 0, max: 59}
+	cronMinute  = cronField{name: "minute", min: 0, max: 59}
+	cronHour    = cronField{name: "hour", min: 0, max: 23}
+	cronDay     = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth   = cronField{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	cronWeekday = cronField{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// cronSchedule is the parsed cron expression, with 5 fields or with 6 fields starting with the seconds.
+type cronSchedule struct {
+	spec    string
+	seconds bool
+	second  cronBits
+	minute  cronBits
+	hour    cronBits
+	day     cronBits
+	month   cronBits
+	weekday cronBits
+	// dayStar and weekdayStar are true if the fields are `*`. If both the day of the month and the day of the week
+	// are restricted, the day matches any of them, like in the classic cron.
+	dayStar     bool
+	weekdayStar bool
+}
+
+// parseCron parses the cron expression, e.g. `*/15 9-17 * * 1-5`, `0 0 12 * * MON` or `@daily`.
+func parseCron(spec string) (cronSchedule, error) {
+	s := cronSchedule{spec: spec}
+	expr := strings.TrimSpace(spec)
+	if strings.HasPrefix(expr, "@") {
+		macro, ok := cronMacros[strings.ToLower(expr)]
+		if !ok {
+			return s, fmt.Errorf("unknown cron macro %s, expected one of @yearly, @annually, @monthly, @weekly, @daily, @midnight or @hourly", expr)
+		}
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+		s.seconds = true
+	default:
+		return s, fmt.Errorf("cron expression %q must have 5 or 6 fields, not %d", spec, len(fields))
+	}
+	var err error
+	for i, f := range []struct {
+		bits  *cronBits
+		field cronField
+	}{
+		{&s.second, cronSecond},
+		{&s.minute, cronMinute},
+		{&s.hour, cronHour},
+		{&s.day, cronDay},
+		{&s.month, cronMonth},
+		{&s.weekday, cronWeekday},
+	} {
+		if *f.bits, err = parseCronField(fields[i], f.field); err != nil {
+			return s, err
+		}
+	}
+	// Sunday is both 0 and 7.
+	if s.weekday.has(7) {
+		s.weekday |= 1
+	}
+	s.dayStar = fields[3] == "*" || fields[3] == "?"
+	s.weekdayStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+// parseCronField parses the list of the values, the ranges and the steps, e.g. `1,5-10,*/15`.
+func parseCronField(text string, field cronField) (cronBits, error) {
+	bits := cronBits(0)
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q of the %s in cron field %q", stepText, field.name, text)
+			}
+		}
+		from, to := field.min, field.max
+		if rangeText != "*" && rangeText != "?" {
+			fromText, toText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if from, err = cronValue(fromText, field); err != nil {
+				return 0, fmt.Errorf("%v in cron field %q", err, text)
+			}
+			switch {
+			case isRange:
+				if to, err = cronValue(toText, field); err != nil {
+					return 0, fmt.Errorf("%v in cron field %q", err, text)
+				}
+				if to < from {
+					return 0, fmt.Errorf("the range %s of the %s ends before it starts in cron field %q", rangeText, field.name, text)
+				}
+			case !hasStep:
+				to = from
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses the number or the name of the value, e.g. `3`, `MAR` or `wed`.
+func cronValue(text string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if name != "" && strings.EqualFold(text, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("invalid %s %q, expected %d-%d", field.name, text, field.min, field.max)
+	}
+	return v, nil
+}
+
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	day, weekday := s.day.has(t.Day()), s.weekday.has(int(t.Weekday()))
+	if s.dayStar || s.weekdayStar {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// seek returns the first time the schedule fires after the timestamp, or before it if forward is false. The fields
+// are matched on the wall clock in the location. The fields that do not match are skipped as a whole, e.g. the rest
+// of the month of a wrong month.
+func (s cronSchedule) seek(t time.Time, forward bool, loc *time.Location) (time.Time, error) {
+	gran := time.Minute
+	if s.seconds {
+		gran = time.Second
+	}
+	origin := t
+	t = t.In(loc).Truncate(gran)
+	if forward {
+		t = t.Add(gran)
+	} else if !t.Before(origin) {
+		t = t.Add(-gran)
+	}
+	limit := origin.AddDate(cronHorizon, 0, 0)
+	if !forward {
+		limit = origin.AddDate(-cronHorizon, 0, 0)
+	}
+	for forward && t.Before(limit) || !forward && t.After(limit) {
+		y, mo, d := t.Date()
+		h, mi, _ := t.Clock()
+		// start and next are the bounds of the first field that does not match.
+		var start, next time.Time
+		switch {
+		case !s.month.has(int(mo)):
+			start, next = time.Date(y, mo, 1, 0, 0, 0, 0, loc), time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			start, next = time.Date(y, mo, d, 0, 0, 0, 0, loc), time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		// The hours and the minutes are added, not set on the wall clock, so the hour repeated when the clock goes
+		// back is the first one.
+		case !s.hour.has(h):
+			start = time.Date(y, mo, d, h, 0, 0, 0, loc)
+			next = start.Add(time.Hour)
+		case !s.minute.has(mi):
+			start = time.Date(y, mo, d, h, mi, 0, 0, loc)
+			next = start.Add(time.Minute)
+		case !s.second.has(t.Second()):
+			start, next = t, t.Add(time.Second)
+		default:
+			return t, nil
+		}
+		// Around the DST changes the wall clock can map to a time on the other side of the timestamp, then it
+		// moves by the smallest step.
+		if forward {
+			if !next.After(t) {
+				next = t.Add(gran)
+			}
+			t = next
+		} else {
+			if start = start.Add(-gran); !start.Before(t) {
+				start = t.Add(-gran)
+			}
+			t = start
+		}
+	}
+	return origin, fmt.Errorf("cron expression %q does not fire within %d years", s.spec, cronHorizon)
+}
+
+// cronNode is the time when the cron schedule fires next or previously, e.g. `cron "0 9 * * 1-5" next`, or
+// the sequence of the times, e.g. `cron "@daily" prev 3 before today`.
+type cronNode struct {
+	schedule cronSchedule
+	forward  bool
+	// count is the length of the sequence. If zero, the result is the single timestamp.
+	count int
+	// ref is the timestamp from which the schedule is searched. If nil, it is now.
+	ref p.Node
+	cur p.Cursor
+}
+
+func (n cronNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n cronNode) String() string {
+	direction := map[bool]string{true: "next", false: "prev"}[n.forward]
+	s := fmt.Sprintf("(cron %q %s", n.schedule.spec, direction)
+	if n.count > 0 {
+		s += fmt.Sprintf(" %d", n.count)
+	}
+	if n.ref != nil {
+		s += fmt.Sprintf(" %s %s", map[bool]string{true: "after", false: "before"}[n.forward], n.ref)
+	}
+	return s + ")"
+}
+
+// buildCron builds the node from the sequence: `cron`, the quoted expression or the macro, `next` or `prev`,
+// the optional count, and the optional `after` or `before` with the timestamp.
+func buildCron(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	if seq.Len() < 3 {
+		expected := []string{"", "cron expression in quotes, e.g. \"*/15 * * * *\", or a macro like @daily", "next or prev"}[seq.Len()]
+		return nil, cursorError{err: fmt.Errorf("expected %s", expected), cur: seq.Cursor()}
+	}
+	spec := seq.Nodes[1].(p.LiteralNode)
+	text := strings.TrimSpace(spec.Literal)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	} else if strings.HasPrefix(text, "'") {
+		text = strings.Trim(text, "'")
+	}
+	schedule, err := parseCron(text)
+	if err != nil {
+		return nil, cursorError{err: err, cur: spec.Cursor()}
+	}
+	n := cronNode{schedule: schedule, forward: strings.TrimSpace(seq.Nodes[2].(p.LiteralNode).Literal) == "next", cur: seq.Cursor()}
+	if seq.Len() > 3 {
+		if count, ok := seq.Nodes[3].(p.LiteralNode); ok {
+			if n.count, err = strconv.Atoi(count.Literal); err != nil || n.count < 1 {
+				return nil, cursorError{err: fmt.Errorf("the count of cron times must be positive, not %s", count.Literal), cur: count.Cursor()}
+			}
+		}
+	}
+	if seq.Len() > 4 {
+		if ref, ok := seq.Nodes[4].(p.SequenceNode); ok {
+			if ref.Len() != 2 {
+				return nil, cursorError{err: fmt.Errorf("expected timestamp after %s", strings.TrimSpace(ref.Nodes[0].(p.LiteralNode).Literal)), cur: ref.Cursor()}
+			}
+			n.ref = ref.Nodes[1]
+		}
+	}
+	return n, nil
+}
+
+// evalCron returns the next or the previous time the schedule fires, or the sequence of the count times in
+// the chronological order.
+func (e evaluator) evalCron(n cronNode) (p.Node, error) {
+	from := p.IsoTimeNode{Time: e.now, Cur: n.cur}
+	if n.ref != nil {
+		value, err := e.eval(n.ref)
+		if err != nil {
+			return nil, err
+		}
+		ref, ok := value.(p.IsoTimeNode)
+		if !ok {
+			return nil, cursorError{err: fmt.Errorf("cron times can be searched only from a timestamp, not %s", withArticle(typeName(value))), cur: n.ref.Cursor()}
+		}
+		from = ref
+	}
+	count := n.count
+	if count == 0 {
+		count = 1
+	}
+	if count > maxSeriesLen {
+		return nil, cursorError{err: fmt.Errorf("the sequence is longer than %d timestamps", maxSeriesLen), cur: n.cur}
+	}
+	times := make([]time.Time, 0, count)
+	t := from.Time
+	for i := 0; i < count; i++ {
+		var err error
+		if t, err = n.schedule.seek(t, n.forward, e.loc); err != nil {
+			return nil, cursorError{err: err, cur: n.cur}
+		}
+		times = append(times, t)
+	}
+	if n.count == 0 {
+		return p.IsoTimeNode{Time: times[0], Cur: n.cur}, nil
+	}
+	if !n.forward {
+		for i, j := 0, len(times)-1; i < j; i, j = i+1, j-1 {
+			times[i], times[j] = times[j], times[i]
+		}
+	}
+	return seriesNode{times: times, end: times[len(times)-1], cur: n.cur}, nil
+}
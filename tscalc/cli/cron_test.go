@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCron(t *testing.T) {
+	// Friday.
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 27, 17, 50, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{`cron "*/15 9-17 * * 1-5" next`, "2023-10-30T09:00:00+00:00"},
+		{`cron "*/15 9-17 * * 1-5" prev`, "2023-10-27T17:45:00+00:00"},
+		{`cron "*/15 9-17 * * 1-5" next 3 after 2023-10-27T16:50:00Z`, "2023-10-27T17:00:00+00:00\n2023-10-27T17:15:00+00:00\n2023-10-27T17:30:00+00:00"},
+		{`cron "*/15 * * * *" next after 2023-10-27T17:00:00Z`, "2023-10-27T17:15:00+00:00"},
+		{`cron "*/15 * * * *" prev before 2023-10-27T17:00:00Z`, "2023-10-27T16:45:00+00:00"},
+		{`cron '0 0 12 * * MON' prev 2 before 2023-10-30T12:00:00Z`, "2023-10-16T12:00:00+00:00\n2023-10-23T12:00:00+00:00"},
+		{`cron "30 * * * * *" next`, "2023-10-27T17:50:30+00:00"},
+		{`cron @daily next in Europe/Warsaw`, "2023-10-28T00:00:00+02:00"},
+		{`cron @weekly next`, "2023-10-29T00:00:00+00:00"},
+		{`cron "0 0 29 2 *" next`, "2024-02-29T00:00:00+00:00"},
+		{`cron "0 9 1 * mon" next 3`, "2023-10-30T09:00:00+00:00\n2023-11-01T09:00:00+00:00\n2023-11-06T09:00:00+00:00"},
+		{`cron "0 9 * jan-mar,dec 0" next`, "2023-12-03T09:00:00+00:00"},
+		{`cron "5/20 * * * *" next 2`, "2023-10-27T18:05:00+00:00\n2023-10-27T18:25:00+00:00"},
+		{`cron "0 2 * * 7" next after today in Europe/Warsaw`, "2023-10-29T02:00:00+02:00"},
+		{`cron @hourly next - now`, "10m0s"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCronErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{`cron "0 0 30 2 *" next`, `cron expression "0 0 30 2 *" does not fire within 5 years`},
+		{`cron "0 0 * * 8" next`, `invalid day of week "8", expected 0-7 in cron field "8"`},
+		{`cron "0 0 * foo *" next`, `invalid month "foo", expected 1-12 in cron field "foo"`},
+		{`cron "0 */0 * * *" next`, `invalid step "0" of the hour in cron field "*/0"`},
+		{`cron "0 17-9 * * *" next`, `the range 17-9 of the hour ends before it starts in cron field "17-9"`},
+		{`cron "* * *" next`, `cron expression "* * *" must have 5 or 6 fields, not 3`},
+		{`cron @reboot next`, "unknown cron macro @reboot, expected one of @yearly, @annually, @monthly, @weekly, @daily, @midnight or @hourly"},
+		{`cron @daily`, "expected next or prev"},
+		{`cron @daily next 0`, "the count of cron times must be positive, not 0"},
+		{`cron @daily next after 1h`, "cron times can be searched only from a timestamp, not a period"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
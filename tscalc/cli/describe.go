@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// describe prints the calendar facts about the timestamp in the output zone, one per line: the week, the day of
+// the year, the quarter, the epoch in all units, the time relative to now and if the local time zone of the machine
+// was in DST, whatever the output zone is.
+func describe(t p.IsoTimeNode, opts options) (string, error) {
+	res, err := formatResult(t, opts)
+	if err != nil {
+		return "", err
+	}
+	local := t.Time.In(opts.loc)
+	year, week := local.ISOWeek()
+	daysInYear := time.Date(local.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	machine := t.Time.In(time.Local)
+	zone, _ := machine.Zone()
+	dst := map[bool]string{true: "yes", false: "no"}[machine.IsDST()]
+
+	b := strings.Builder{}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, row := range [][2]string{
+		{"time", res},
+		{"weekday", local.Weekday().String()},
+		{"iso week", fmt.Sprintf("%d-W%02d", year, week)},
+		{"day of year", fmt.Sprintf("%d of %d", local.YearDay(), daysInYear)},
+		{"quarter", fmt.Sprintf("Q%d", (int(local.Month())+2)/3)},
+		{"unix", fmt.Sprint(t.Time.Unix())},
+		{"unix ms", fmt.Sprint(t.Time.UnixMilli())},
+		{"unix ns", fmt.Sprint(t.Time.UnixNano())},
+		{"relative", describeRelative(t.Time, nowFunc())},
+		{"local dst", fmt.Sprintf("%s (%s)", dst, zone)},
+	} {
+		fmt.Fprintf(w, "%s\t%s\n", row[0], row[1])
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// relativeUnits are the units of the relative times, from the largest. The months and the years are approximate.
+var relativeUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// describeRelative describes the time relative to now in the largest whole unit, e.g. "3 hours ago" or "in 2 days".
+func describeRelative(t, now time.Time) string {
+	// The now of the expression is a moment earlier, so `now + 2d` would be 1 day and 23 hours away.
+	d := t.Sub(now).Round(time.Second)
+	abs := d
+	if abs < 0 {
+		abs = -abs
+	}
+	for _, unit := range relativeUnits {
+		if n := abs / unit.d; n > 0 {
+			text := fmt.Sprintf("%d %s", n, unit.name)
+			if n > 1 {
+				text += "s"
+			}
+			if d < 0 {
+				return text + " ago"
+			}
+			return "in " + text
+		}
+	}
+	return "now"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 22, 40, 9, 0, time.UTC)
+	}
+	// The DST is of the local zone, not of the output zone.
+	local := time.Local
+	defer func() { time.Local = local }()
+	var err error
+	time.Local, err = time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	s := newSession()
+	s.describe = true
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"2023-10-29T19:40:09Z", `time         2023-10-29T19:40:09+00:00
+weekday      Sunday
+iso week     2023-W43
+day of year  302 of 365
+quarter      Q4
+unix         1698608409
+unix ms      1698608409000
+unix ns      1698608409000000000
+relative     3 hours ago
+local dst    yes (EDT)`},
+		{"2024-01-01T00:30:00+01:00 in Europe/Warsaw", `time         2024-01-01T00:30:00+01:00
+weekday      Monday
+iso week     2024-W01
+day of year  1 of 366
+quarter      Q1
+unix         1704065400
+unix ms      1704065400000
+unix ns      1704065400000000000
+relative     in 2 months
+local dst    no (EST)`},
+		{"1h", "1h0m0s"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := s.handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDescribeRelative(t *testing.T) {
+	now := time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)
+	for _, tc := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "now"},
+		{300 * time.Millisecond, "now"},
+		{time.Second, "in 1 second"},
+		{-90 * time.Minute, "1 hour ago"},
+		{48*time.Hour - time.Millisecond, "in 2 days"},
+		{-400 * 24 * time.Hour, "1 year ago"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, describeRelative(now.Add(tc.d), now))
+		})
+	}
+}
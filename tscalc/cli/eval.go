@@ -0,0 +1,657 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	strPlus     = "+"
+	strMinus    = "-"
+	strMultiply = "*"
+	strDivide   = "/"
+	strFloor    = "floor"
+	strCeil     = "ceil"
+	strRound    = "round"
+	strNow      = "now"
+)
+
+// binaryNode is an operation on two nodes, e.g. `now - 1h`.
+type binaryNode struct {
+	left  p.Node
+	op    p.LiteralNode
+	right p.Node
+}
+
+// Cursor points at the operator, so the errors of the operation are shown there.
+func (n binaryNode) Cursor() p.Cursor {
+	return opCursor(n.op)
+}
+
+func (n binaryNode) String() string {
+	return fmt.Sprintf("(%s %s %s)", n.left, n.op.Literal, n.right)
+}
+
+// unaryNode is a sign in front of a node, e.g. `-1h`.
+type unaryNode struct {
+	op   p.LiteralNode
+	node p.Node
+}
+
+func (n unaryNode) Cursor() p.Cursor {
+	return opCursor(n.op)
+}
+
+func (n unaryNode) String() string {
+	return fmt.Sprintf("(%s%s)", n.op.Literal, n.node)
+}
+
+// scalarNode is a plain number, e.g. a factor in multiplication or the ratio of two periods.
+type scalarNode struct {
+	value float64
+	cur   p.Cursor
+}
+
+func (n scalarNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n scalarNode) String() string {
+	return strconv.FormatFloat(n.value, 'f', -1, 64)
+}
+
+// opCursor returns the cursor pointing at the operator itself, skipping the whitespace matched before it.
+func opCursor(op p.LiteralNode) p.Cursor {
+	cur := op.Cursor()
+	if i := strings.Index(cur.String(), op.Literal); i > 0 {
+		cur = cur.Advance(i)
+	}
+	return cur
+}
+
+// foldBinary turns the sequence of the first operand and the repeated [operator operand] pairs into
+// a left-associative tree of binary nodes.
+func foldBinary(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode).RemoveEmpty()
+	acc := seq.Nodes[0]
+	if seq.Len() == 1 {
+		return acc, nil
+	}
+	for _, pair := range seq.Nodes[1].(p.SequenceNode).Nodes {
+		pairSeq := pair.(p.SequenceNode)
+		op := pairSeq.Nodes[0].(p.LiteralNode)
+		// The sequence is not complete if the input ended after the operator.
+		if pairSeq.Len() != 2 {
+			return nil, cursorError{err: fmt.Errorf("expected operand after %s", op.Literal), cur: opCursor(op)}
+		}
+		acc = binaryNode{left: acc, op: op, right: pairSeq.Nodes[1]}
+	}
+	return acc, nil
+}
+
+func buildUnary(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	op := seq.Nodes[0].(p.LiteralNode)
+	if seq.Len() != 2 {
+		return nil, cursorError{err: fmt.Errorf("expected operand after %s", op.Literal), cur: opCursor(op)}
+	}
+	if op.Literal == strPlus {
+		return seq.Nodes[1], nil
+	}
+	return unaryNode{op: op, node: seq.Nodes[1]}, nil
+}
+
+func buildParens(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	if seq.Len() != 3 {
+		return nil, cursorError{err: fmt.Errorf("missing closing parenthesis"), cur: seq.Cursor()}
+	}
+	return seq.Nodes[1], nil
+}
+
+// evaluator computes the value of the syntax tree. The location is used to add the calendar periods, e.g. to add
+// a day across the DST change.
+type evaluator struct {
+	now time.Time
+	loc *time.Location
+	// vars are the values of the variables.
+	vars map[string]p.Node
+	// row are the columns referenced as $1, $2... with -e, parsed with the values parser.
+	row    []string
+	values p.Parser
+}
+
+// eval returns timestamp (IsoTimeNode), period (PeriodNode), number (scalarNode), interval (intervalNode) or
+// boolean (boolNode).
+func (e evaluator) eval(node p.Node) (p.Node, error) {
+	value, err := e.evalNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return e.toValue(value, false), nil
+}
+
+func (e evaluator) evalNode(node p.Node) (p.Node, error) {
+	switch n := node.(type) {
+	case binaryNode:
+		left, err := e.evalNode(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.evalNode(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return e.combine(left, n.op, right)
+	case unaryNode:
+		operand, err := e.evalNode(n.node)
+		if err != nil {
+			return nil, err
+		}
+		return e.negate(operand, n)
+	case lengthNode:
+		operand, err := e.evalNode(n.node)
+		if err != nil {
+			return nil, err
+		}
+		interval, ok := e.toValue(operand, false).(intervalNode)
+		if !ok {
+			return nil, cursorError{err: fmt.Errorf("cannot take the length of %s", withArticle(typeName(operand))), cur: n.Cursor()}
+		}
+		return intervalLength(interval), nil
+	case everyNode:
+		return e.evalEvery(n)
+	case workNode:
+		return e.evalWork(n)
+	case cronNode:
+		return e.evalCron(n)
+	case claimNode:
+		return e.evalClaim(n)
+	case variableNode:
+		return e.vars[n.name], nil
+	case fieldNode:
+		return e.fieldValue(n)
+	}
+	return node, nil
+}
+
+// resolver is a term whose time depends on the current time and the location, e.g. `today` or the timestamp
+// without the year.
+type resolver interface {
+	Resolve(now time.Time, loc *time.Location) p.IsoTimeNode
+}
+
+// toValue converts the terms to the values the operators work on. The epoch numbers are timestamps, unless they
+// are used as plain numbers, e.g. in multiplication.
+func (e evaluator) toValue(node p.Node, asScalar bool) p.Node {
+	switch n := node.(type) {
+	case p.EpochTimeNode:
+		if asScalar {
+			return scalarNode{value: n.Float(), cur: n.Cursor()}
+		}
+		return n.ToIsoTimeNode()
+	case resolver:
+		return n.Resolve(e.now, e.loc)
+	case p.LiteralNode:
+		if n.Literal == strNow {
+			return p.IsoTimeNode{Time: e.now, Cur: n.Cursor()}
+		}
+	}
+	return node
+}
+
+// logTerms logs the formats in which the terms were parsed.
+func logTerms(node p.Node) {
+	switch n := node.(type) {
+	case binaryNode:
+		logTerms(n.left)
+		logTerms(n.right)
+	case unaryNode:
+		logTerms(n.node)
+	case lengthNode:
+		logTerms(n.node)
+	case workNode:
+		logTerms(n.node)
+	case cronNode:
+		log.Printf("Term at %d is cron schedule %q", n.Cursor().Pos, n.schedule.spec)
+		if n.ref != nil {
+			logTerms(n.ref)
+		}
+	case claimNode:
+		log.Printf("Term at %d is %s claim", n.Cursor().Pos, n.name)
+	case assignNode:
+		logTerms(n.value)
+	case variableNode:
+		log.Printf("Term at %d is variable %s", n.Cursor().Pos, n)
+	case fieldNode:
+		log.Printf("Term at %d is column %s", n.Cursor().Pos, n)
+	case everyNode:
+		logTerms(n.step)
+		logTerms(n.from)
+		logTerms(n.to)
+	case p.IsoTimeNode:
+		log.Printf("Term at %d matched %s format: %s", n.Cursor().Pos, n.Format, n)
+	case p.PartialTimeNode:
+		log.Printf("Term at %d matched %s format, inferred %s: %s", n.Cursor().Pos, n.Format, strings.Join(n.Inferred(), " and "), n)
+	case p.AnchorNode:
+		log.Printf("Term at %d matched anchor: %s", n.Cursor().Pos, n)
+	case p.RelativeTimeNode:
+		log.Printf("Term at %d matched relative time: %s", n.Cursor().Pos, n)
+	case p.EpochTimeNode:
+		log.Printf("Term at %d matched epoch format in %s: %s", n.Cursor().Pos, n.Unit(), n)
+	case p.PeriodNode:
+		log.Printf("Term at %d matched period: %s", n.Cursor().Pos, n)
+	case businessNode:
+		log.Printf("Term at %d matched business period: %s", n.Cursor().Pos, n)
+	}
+}
+
+func (e evaluator) negate(node p.Node, unary unaryNode) (p.Node, error) {
+	switch n := e.toValue(node, true).(type) {
+	case p.PeriodNode:
+		return n.Neg(), nil
+	case businessNode:
+		return n.neg(), nil
+	case scalarNode:
+		n.value = -n.value
+		return n, nil
+	}
+	return nil, cursorError{err: fmt.Errorf("cannot negate %s", withArticle(typeName(node))), cur: unary.Cursor()}
+}
+
+func isScalar(node p.Node) bool {
+	_, ok := node.(scalarNode)
+	return ok
+}
+
+// combine performs the operation on two values.
+func (e evaluator) combine(leftNode p.Node, literal p.LiteralNode, rightNode p.Node) (p.Node, error) {
+	log.Printf("Combine %s (%T) %s %s (%T)", leftNode, leftNode, literal, rightNode, rightNode)
+	op := literal.Literal
+	asScalar := op == strMultiply || op == strDivide || isScalar(leftNode) || isScalar(rightNode)
+	leftNode = e.toValue(leftNode, asScalar)
+	rightNode = e.toValue(rightNode, asScalar)
+	opErr := func(err error) error {
+		return cursorError{err: err, cur: opCursor(literal)}
+	}
+
+	switch {
+	case isComparison(op):
+		result, err := compare(leftNode, op, rightNode)
+		if err != nil {
+			return nil, opErr(err)
+		}
+		return result, nil
+	case isIntervalOp(op):
+		result, err := combineIntervals(leftNode, op, rightNode)
+		if err != nil {
+			return nil, opErr(err)
+		}
+		return result, nil
+	}
+
+	switch left := leftNode.(type) {
+	case p.PeriodNode:
+		switch right := rightNode.(type) {
+		case p.PeriodNode:
+			switch op {
+			case strPlus:
+				sum := left.Add(right)
+				sum.Cur = right.Cursor()
+				return sum, nil
+			case strMinus:
+				sum := left.Add(right.Neg())
+				sum.Cur = right.Cursor()
+				return sum, nil
+			case strDivide:
+				ratio, err := periodRatio(left, right)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return scalarNode{value: ratio, cur: right.Cursor()}, nil
+			case strFloor, strCeil, strRound:
+				rounded, err := roundPeriod(left, op, right)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return rounded, nil
+			}
+		case p.IsoTimeNode:
+			if op == strPlus {
+				return p.IsoTimeNode{
+					Time: left.AddTo(right.Time, e.loc),
+					Cur:  right.Cursor(),
+				}, nil
+			}
+		case scalarNode:
+			switch op {
+			case strMultiply:
+				product, err := multiplyPeriod(left, right.value)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return product, nil
+			case strDivide:
+				quotient, err := dividePeriod(left, right.value)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return quotient, nil
+			}
+		}
+	case p.IsoTimeNode:
+		switch right := rightNode.(type) {
+		case p.PeriodNode:
+			switch op {
+			case strPlus:
+				return p.IsoTimeNode{
+					Time: right.AddTo(left.Time, e.loc),
+					Cur:  right.Cursor(),
+				}, nil
+			case strMinus:
+				return p.IsoTimeNode{
+					Time: right.Neg().AddTo(left.Time, e.loc),
+					Cur:  right.Cursor(),
+				}, nil
+			case strFloor, strCeil, strRound:
+				rounded, err := roundTime(left.Time, op, right, e.loc)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return p.IsoTimeNode{Time: rounded, Cur: right.Cursor()}, nil
+			}
+		case businessNode:
+			if op == strPlus || op == strMinus {
+				if op == strMinus {
+					right = right.neg()
+				}
+				t, err := calendar.add(left.Time, right, e.loc)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return p.IsoTimeNode{Time: t, Cur: right.Cursor()}, nil
+			}
+		case p.IsoTimeNode:
+			switch op {
+			case strMinus:
+				return p.PeriodNode{
+					Duration: left.Time.Sub(right.Time),
+					Since:    right.Time,
+					Cur:      right.Cur,
+				}, nil
+			case strRange:
+				interval, err := newInterval(left, right)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return interval, nil
+			}
+		}
+	case businessNode:
+		if right, ok := rightNode.(p.IsoTimeNode); ok && op == strPlus {
+			t, err := calendar.add(right.Time, left, e.loc)
+			if err != nil {
+				return nil, opErr(err)
+			}
+			return p.IsoTimeNode{Time: t, Cur: right.Cursor()}, nil
+		}
+	case scalarNode:
+		switch right := rightNode.(type) {
+		case scalarNode:
+			var value float64
+			switch op {
+			case strPlus:
+				value = left.value + right.value
+			case strMinus:
+				value = left.value - right.value
+			case strMultiply:
+				value = left.value * right.value
+			case strDivide:
+				if right.value == 0 {
+					return nil, opErr(fmt.Errorf("division by zero"))
+				}
+				value = left.value / right.value
+			default:
+				return nil, opErr(operationError(leftNode, op, rightNode))
+			}
+			return scalarNode{value: value, cur: right.Cursor()}, nil
+		case p.PeriodNode:
+			if op == strMultiply {
+				product, err := multiplyPeriod(right, left.value)
+				if err != nil {
+					return nil, opErr(err)
+				}
+				return product, nil
+			}
+		}
+	}
+	return nil, opErr(operationError(leftNode, op, rightNode))
+}
+
+// typeName returns the name of the type of the value used in the error messages.
+func typeName(node p.Node) string {
+	switch node.(type) {
+	case p.IsoTimeNode, p.EpochTimeNode, resolver, p.LiteralNode:
+		return "timestamp"
+	case p.PeriodNode:
+		return "period"
+	case businessNode:
+		return "business period"
+	case scalarNode:
+		return "number"
+	case intervalNode:
+		return "interval"
+	case boolNode:
+		return "boolean"
+	case seriesNode:
+		return "sequence"
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+func withArticle(name string) string {
+	if strings.IndexAny(name[:1], "aeiou") == 0 {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+func operationError(left p.Node, op string, right p.Node) error {
+	l, r := typeName(left), typeName(right)
+	switch {
+	case isComparison(op):
+		return fmt.Errorf("cannot compare %s and %s", withArticle(l), withArticle(r))
+	case op == strRange:
+		return fmt.Errorf("cannot make an interval of %s and %s", withArticle(l), withArticle(r))
+	case op == strIn:
+		return fmt.Errorf("cannot test if %s is in %s", withArticle(l), withArticle(r))
+	case op == strOverlaps:
+		return fmt.Errorf("cannot test if %s overlaps %s", withArticle(l), withArticle(r))
+	case op == strUnion || op == strIntersect:
+		return fmt.Errorf("cannot %s %s and %s", op, withArticle(l), withArticle(r))
+	case op == strFloor || op == strCeil || op == strRound:
+		return fmt.Errorf("cannot %s %s to %s", op, withArticle(l), withArticle(r))
+	case op == strMinus:
+		return fmt.Errorf("cannot subtract %s from %s", withArticle(r), withArticle(l))
+	}
+	verb := map[string]string{strPlus: "add", strMultiply: "multiply", strDivide: "divide"}[op]
+	if l == r {
+		return fmt.Errorf("cannot %s two %ss", verb, l)
+	}
+	if op == strDivide {
+		return fmt.Errorf("cannot divide %s by %s", withArticle(l), withArticle(r))
+	}
+	return fmt.Errorf("cannot %s %s and %s", verb, withArticle(l), withArticle(r))
+}
+
+// multiplyPeriod scales the period. The calendar part can be multiplied only by integers.
+func multiplyPeriod(period p.PeriodNode, factor float64) (p.PeriodNode, error) {
+	period.Since = time.Time{}
+	if period.IsCalendar() {
+		if factor != math.Trunc(factor) {
+			return period, fmt.Errorf("cannot multiply calendar period %s by %s", period, scalarNode{value: factor})
+		}
+		period.Months *= int(factor)
+		period.Days *= int(factor)
+	}
+	period.Duration = time.Duration(math.Round(float64(period.Duration) * factor))
+	return period, nil
+}
+
+// dividePeriod divides the period. The calendar part can be divided only if the result is a whole number of months
+// and days.
+func dividePeriod(period p.PeriodNode, divisor float64) (p.PeriodNode, error) {
+	period.Since = time.Time{}
+	if divisor == 0 {
+		return period, fmt.Errorf("division by zero")
+	}
+	if period.IsCalendar() {
+		k := int(divisor)
+		if float64(k) != divisor || period.Months%k != 0 || period.Days%k != 0 {
+			return period, fmt.Errorf("cannot divide calendar period %s by %s", period, scalarNode{value: divisor})
+		}
+		period.Months /= k
+		period.Days /= k
+	}
+	period.Duration = time.Duration(math.Round(float64(period.Duration) / divisor))
+	return period, nil
+}
+
+// periodRatio divides two periods. The calendar periods do not have a fixed length, so they cannot be divided.
+func periodRatio(left, right p.PeriodNode) (float64, error) {
+	if left.IsCalendar() || right.IsCalendar() {
+		return 0, fmt.Errorf("cannot divide calendar periods %s and %s", left, right)
+	}
+	if right.Duration == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return float64(left.Duration) / float64(right.Duration), nil
+}
+
+// roundUnit parses the name of the unit to round to, e.g. `ceil to day`. The name is the period of one unit.
+var roundUnit = p.Map(
+	p.RegexGroup(`(second|minute|hour|day|week|month|year)\b`),
+	func(node p.Node) (p.Node, error) {
+		unit := node.(p.LiteralNode)
+		period := map[string]p.PeriodNode{
+			"second": {Duration: time.Second},
+			"minute": {Duration: time.Minute},
+			"hour":   {Duration: time.Hour},
+			"day":    {Days: 1},
+			"week":   {Days: 7},
+			"month":  {Months: 1},
+			"year":   {Months: 12},
+		}[unit.Literal]
+		period.Cur = unit.Cursor()
+		return period, nil
+	},
+)
+
+// calendarUnit returns the name of the calendar unit of the period. Only the periods of exactly one day, week,
+// month or year are calendar units.
+func calendarUnit(period p.PeriodNode) (string, bool) {
+	if period.Duration != 0 {
+		return "", false
+	}
+	switch {
+	case period.Months == 0 && period.Days == 1:
+		return "day", true
+	case period.Months == 0 && period.Days == 7:
+		return "week", true
+	case period.Months == 1 && period.Days == 0:
+		return "month", true
+	case period.Months == 12 && period.Days == 0:
+		return "year", true
+	}
+	return "", false
+}
+
+// roundTime rounds the timestamp to the multiple of the period. The calendar units are aligned to the calendar in
+// the location, e.g. `floor 1d` is the midnight and `floor 1w` is the Monday. The fixed periods are aligned to the
+// wall clock since the midnight in the location, so `floor 1h` is the full hour also in the zones with a half-hour
+// offset, and `floor 7m` starts over every day. The halfway values are rounded up.
+func roundTime(t time.Time, op string, unit p.PeriodNode, loc *time.Location) (time.Time, error) {
+	if name, ok := calendarUnit(unit); ok {
+		start, next := p.UnitBounds(t.In(loc), name)
+		switch {
+		case op == strFloor || t.Equal(start):
+			return start, nil
+		case op == strCeil || t.Sub(start) >= next.Sub(t):
+			return next, nil
+		}
+		return start, nil
+	}
+	if unit.IsCalendar() {
+		return t, fmt.Errorf("cannot %s to calendar period %s, only to 1d, 1w, 1mo or 1y", op, unit)
+	}
+	if unit.Duration <= 0 {
+		return t, fmt.Errorf("cannot %s to %s, the period must be positive", op, unit)
+	}
+	// The zone offset is the one at the rounded time, which differs from the offset of t across a DST change.
+	local := t.In(loc)
+	year, month, day := local.Date()
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+	floorWall := sinceMidnight - sinceMidnight%unit.Duration
+	floor := wallTime(local, floorWall)
+	// The last unit of the day ends at the next midnight.
+	ceil := time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	if ceilWall := floorWall + unit.Duration; ceilWall < 24*time.Hour {
+		ceil = wallTime(local, ceilWall)
+	}
+	switch {
+	case op == strFloor || t.Equal(floor):
+		return floor, nil
+	case op == strCeil || t.Sub(floor) >= ceil.Sub(t):
+		return ceil, nil
+	}
+	return floor, nil
+}
+
+// wallTime returns the time on the wall clock since the midnight of the day of t, in the location of t. The wall
+// clock repeated when the clocks go back resolves to the zone offset of t, so rounding within that hour does not
+// jump by an hour.
+func wallTime(t time.Time, wall time.Duration) time.Time {
+	year, month, day := t.Date()
+	res := time.Date(year, month, day, 0, 0, 0, int(wall), t.Location())
+	name, offset := t.Zone()
+	same := time.Date(year, month, day, 0, 0, 0, int(wall), time.FixedZone(name, offset)).In(t.Location())
+	if _, sameOffset := same.Zone(); sameOffset == offset {
+		return same
+	}
+	return res
+}
+
+// roundPeriod rounds the period to the multiple of the other period. The floor and ceil go towards the negative and
+// the positive infinity, the halfway values are rounded away from zero. The calendar periods cannot be rounded.
+func roundPeriod(period p.PeriodNode, op string, unit p.PeriodNode) (p.PeriodNode, error) {
+	if period.IsCalendar() || unit.IsCalendar() {
+		return period, fmt.Errorf("cannot %s calendar periods %s and %s", op, period, unit)
+	}
+	if unit.Duration <= 0 {
+		return period, fmt.Errorf("cannot %s to %s, the period must be positive", op, unit)
+	}
+	d := period.Duration
+	switch op {
+	case strFloor:
+		rounded := d.Truncate(unit.Duration)
+		if rounded > d {
+			rounded -= unit.Duration
+		}
+		period.Duration = rounded
+	case strCeil:
+		rounded := d.Truncate(unit.Duration)
+		if rounded < d {
+			rounded += unit.Duration
+		}
+		period.Duration = rounded
+	case strRound:
+		period.Duration = d.Round(unit.Duration)
+	}
+	return period, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"sort"
+	"strings"
+	"time"
+)
+
+// events analyses the stream of timestamps, e.g. the times of the log lines, to find the gaps between them and
+// the number of the timestamps in the periods.
+type events struct {
+	// gaps is the shortest gap that is reported. If zero, the gaps are not reported.
+	gaps time.Duration
+	// rate is the period in which the timestamps are counted. If zero, the rate is not reported.
+	rate p.PeriodNode
+	// sort sorts the timestamps before the analysis. Otherwise the timestamps before the previous one are
+	// reported as errors and skipped.
+	sort bool
+	// column is the number of the column with the timestamp, counted from 1. If zero, the whole line is
+	// the timestamp.
+	column int
+	delim  rune
+}
+
+// parseGaps parses the shortest reported gap, e.g. "5m".
+func parseGaps(s string) (time.Duration, error) {
+	period, err := parsePeriodFlag("gaps", s)
+	if err != nil {
+		return 0, err
+	}
+	if period.IsCalendar() || period.Duration <= 0 {
+		return 0, fmt.Errorf("the gap must be a positive fixed period, not %s", period)
+	}
+	return period.Duration, nil
+}
+
+// parseRate parses the period in which the timestamps are counted. It is a positive fixed period, e.g. "1m",
+// or a calendar unit: 1d, 1w, 1mo or 1y.
+func parseRate(s string) (p.PeriodNode, error) {
+	period, err := parsePeriodFlag("rate", s)
+	if err != nil {
+		return p.PeriodNode{}, err
+	}
+	if _, ok := calendarUnit(period); !ok && (period.IsCalendar() || period.Duration <= 0) {
+		return p.PeriodNode{}, fmt.Errorf("the rate must be per a positive fixed period or per 1d, 1w, 1mo or 1y, not %s", period)
+	}
+	return period, nil
+}
+
+func parsePeriodFlag(name, s string) (p.PeriodNode, error) {
+	node, rest, err := p.Period.Parse(p.NewCursor(s))
+	if err != nil || node == nil || !rest.Ended() {
+		return p.PeriodNode{}, fmt.Errorf("invalid %s %q, expected a period like 5m", name, s)
+	}
+	return node.(p.PeriodNode), nil
+}
+
+// run reads the timestamps and prints the first and the last of them, the gaps and the rate. The lines that are not
+// timestamps are reported to errOut and skipped. It returns the number of such lines.
+func (ev events) run(in io.Reader, out, errOut io.Writer) (int, error) {
+	times, failed, err := ev.read(in, errOut)
+	if err != nil {
+		return failed, err
+	}
+	if ev.sort {
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	}
+	res, err := ev.format(times, defaultOptions)
+	if err != nil {
+		return failed, err
+	}
+	fmt.Fprint(out, res)
+	return failed, nil
+}
+
+// read returns the timestamps of the input, in the input order.
+func (ev events) read(in io.Reader, errOut io.Writer) ([]time.Time, int, error) {
+	// next returns the columns of the next line, or io.EOF. Without the column, the whole line is the only column.
+	var next func() ([]string, error)
+	column := ev.column
+	if column == 0 {
+		column = 1
+		scanner := bufio.NewScanner(in)
+		next = func() ([]string, error) {
+			if scanner.Scan() {
+				return []string{scanner.Text()}, nil
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	} else {
+		reader := csv.NewReader(in)
+		reader.Comma = ev.delim
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		next = reader.Read
+	}
+
+	values := valueParser(defaultOptions)
+	e := evaluator{now: nowFunc(), loc: defaultOptions.loc}
+	times := []time.Time{}
+	failed := 0
+	for lineNum := 1; ; lineNum++ {
+		row, err := next()
+		if err == io.EOF {
+			return times, failed, nil
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return times, failed, err
+		}
+		var t time.Time
+		switch {
+		case err != nil:
+		case ev.column == 0 && strings.TrimSpace(row[0]) == "":
+			continue
+		case column > len(row):
+			err = fmt.Errorf("the row has only %d columns", len(row))
+		default:
+			t, err = ev.parseTimestamp(strings.TrimSpace(row[column-1]), values, e)
+		}
+		if err == nil && !ev.sort && len(times) > 0 && t.Before(times[len(times)-1]) {
+			err = fmt.Errorf("%s is before the previous timestamp %s, use -sort for the unsorted input",
+				p.IsoTimeNode{Time: t}, p.IsoTimeNode{Time: times[len(times)-1]})
+		}
+		if err != nil {
+			fmt.Fprintf(errOut, "line %d: %v\n", lineNum, err)
+			failed++
+			continue
+		}
+		times = append(times, t)
+	}
+}
+
+// parseTimestamp parses the timestamp in any of the supported formats.
+func (ev events) parseTimestamp(text string, values p.Parser, e evaluator) (time.Time, error) {
+	node, rest, err := values.Parse(p.NewCursor(text))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if node == nil || !rest.Ended() {
+		return time.Time{}, fmt.Errorf("cannot parse %q", text)
+	}
+	t, ok := e.toValue(node, false).(p.IsoTimeNode)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a timestamp, not %s", withArticle(typeName(node)))
+	}
+	return t.Time, nil
+}
+
+// format prints the summary of the sorted timestamps, the gaps longer than the threshold, and the number of
+// the timestamps in each period between the first and the last timestamp, including the empty periods.
+func (ev events) format(times []time.Time, opts options) (string, error) {
+	formatTime := func(t time.Time) string {
+		res, _ := formatResult(p.IsoTimeNode{Time: t}, opts)
+		return res
+	}
+	formatPeriod := func(d time.Duration) (string, error) {
+		return formatResult(p.PeriodNode{Duration: d}, opts)
+	}
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "count   %d\n", len(times))
+	if len(times) == 0 {
+		return b.String(), nil
+	}
+	first, last := times[0], times[len(times)-1]
+	span, err := formatPeriod(last.Sub(first))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "first   %s\nlast    %s\nspan    %s\n", formatTime(first), formatTime(last), span)
+
+	if ev.gaps > 0 {
+		threshold, err := formatPeriod(ev.gaps)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "\ngaps longer than %s\n", threshold)
+		for i := 1; i < len(times); i++ {
+			if gap := times[i].Sub(times[i-1]); gap > ev.gaps {
+				length, err := formatPeriod(gap)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&b, "%s .. %s\t%s\n", formatTime(times[i-1]), formatTime(times[i]), length)
+			}
+		}
+	}
+
+	if ev.rate.IsCalendar() || ev.rate.Duration > 0 {
+		counts, err := rate(times, ev.rate, opts.loc)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "\nrate per %s\n", ev.rate)
+		for _, c := range counts {
+			fmt.Fprintf(&b, "%s\t%d\n", formatTime(c.from), c.count)
+		}
+	}
+	return b.String(), nil
+}
+
+// rateBucket is the number of the timestamps in the period starting at from.
+type rateBucket struct {
+	from  time.Time
+	count int
+}
+
+// rate counts the sorted timestamps in the periods aligned like in `floor`, from the period of the first timestamp
+// to the period of the last one.
+func rate(times []time.Time, period p.PeriodNode, loc *time.Location) ([]rateBucket, error) {
+	buckets := []rateBucket{}
+	for _, t := range times {
+		from, err := roundTime(t, strFloor, period, loc)
+		if err != nil {
+			return nil, err
+		}
+		// The empty periods since the previous timestamp are added too, as they show the outages.
+		for len(buckets) > 0 && buckets[len(buckets)-1].from.Before(from) {
+			last := buckets[len(buckets)-1].from
+			// The ceil of the moment after the start is the start of the next period, also for the calendar units.
+			next, err := roundTime(last.Add(time.Nanosecond), strCeil, period, loc)
+			if err != nil {
+				return nil, err
+			}
+			if len(buckets) >= maxSeriesLen {
+				return nil, fmt.Errorf("the rate has more than %d periods, use a longer period", maxSeriesLen)
+			}
+			buckets = append(buckets, rateBucket{from: next})
+		}
+		if len(buckets) == 0 {
+			buckets = append(buckets, rateBucket{from: from})
+		}
+		buckets[len(buckets)-1].count++
+	}
+	return buckets, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	p "lib/tscalc/parse"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate(t *testing.T) {
+	times := []time.Time{
+		time.Date(2023, 10, 29, 18, 19, 24, 0, time.UTC),
+		time.Date(2023, 10, 29, 18, 19, 59, 0, time.UTC),
+		time.Date(2023, 10, 29, 18, 23, 0, 0, time.UTC),
+	}
+	minute := func(m int) time.Time {
+		return time.Date(2023, 10, 29, 18, m, 0, 0, time.UTC)
+	}
+	counts, err := rate(times, p.PeriodNode{Duration: time.Minute}, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, []rateBucket{{minute(19), 2}, {minute(20), 0}, {minute(21), 0}, {minute(22), 0}, {minute(23), 1}}, counts)
+
+	// The days are aligned to the midnight in the location, also across the DST change.
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	counts, err = rate(times[:1], p.PeriodNode{Days: 1}, warsaw)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(counts))
+	assert.Equal(t, time.Date(2023, 10, 29, 0, 0, 0, 0, warsaw), counts[0].from)
+
+	_, err = rate([]time.Time{times[0], times[0].AddDate(1, 0, 0)}, p.PeriodNode{Duration: time.Second}, time.UTC)
+	assert.EqualError(t, err, "the rate has more than 100000 periods, use a longer period")
+}
+
+func TestParseGapsAndRate(t *testing.T) {
+	gap, err := parseGaps("5m")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, gap)
+	_, err = parseGaps("1d")
+	assert.EqualError(t, err, "the gap must be a positive fixed period, not 1d")
+	_, err = parseGaps("five")
+	assert.EqualError(t, err, `invalid gaps "five", expected a period like 5m`)
+
+	r, err := parseRate("1w")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, r.Days)
+	_, err = parseRate("2mo")
+	assert.EqualError(t, err, "the rate must be per a positive fixed period or per 1d, 1w, 1mo or 1y, not 2mo")
+}
+
+func TestEvents(t *testing.T) {
+	input := strings.Join([]string{
+		"2023-10-29T18:19:24Z",
+		"1698603600",
+		"",
+		"2023-10-29 18:30:00",
+		"1h",
+		"2023-10-29T18:29:00Z",
+		"2023-10-29T18:31:10Z",
+	}, "\n")
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	failed, err := events{gaps: 5 * time.Minute, rate: p.PeriodNode{Duration: 4 * time.Minute}}.run(strings.NewReader(input), &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, `line 5: expected a timestamp, not a period
+line 6: 2023-10-29T18:29:00+00:00 is before the previous timestamp 2023-10-29T18:30:00+00:00, use -sort for the unsorted input
+`, errOut.String())
+	assert.Equal(t, `count   4
+first   2023-10-29T18:19:24+00:00
+last    2023-10-29T18:31:10+00:00
+span    11m46s
+
+gaps longer than 5m0s
+2023-10-29T18:20:00+00:00 .. 2023-10-29T18:30:00+00:00	10m0s
+
+rate per 4m0s
+2023-10-29T18:16:00+00:00	1
+2023-10-29T18:20:00+00:00	1
+2023-10-29T18:24:00+00:00	0
+2023-10-29T18:28:00+00:00	2
+`, out.String())
+}
+
+func TestEventsSortedColumn(t *testing.T) {
+	input := "a,2023-10-29T18:30:00Z\nb,2023-10-29T18:19:24Z\nc\nd,2023-10-29T18:29:00Z\n"
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	failed, err := events{gaps: 5 * time.Minute, sort: true, column: 2, delim: ','}.run(strings.NewReader(input), &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, "line 3: the row has only 1 columns\n", errOut.String())
+	assert.Equal(t, `count   3
+first   2023-10-29T18:19:24+00:00
+last    2023-10-29T18:30:00+00:00
+span    10m36s
+
+gaps longer than 5m0s
+2023-10-29T18:19:24+00:00 .. 2023-10-29T18:29:00+00:00	9m36s
+`, out.String())
+
+	out.Reset()
+	failed, err = events{gaps: time.Minute}.run(strings.NewReader(""), &out, &errOut)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, "count   0\n", out.String())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The epoch numbers outside of this range are not timestamps in the filter mode, e.g. the ports, the sizes or
+// the process ids.
+var (
+	filterEpochMin = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	filterEpochMax = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// filterParser returns the parser of the timestamps recognized in the filter mode. The periods and the relative
+// terms like `now` or `today` are not timestamps in the free text.
+func filterParser(opts options) p.Parser {
+	return p.FirstOf(
+		p.IsoTime,
+		p.SqlTime,
+		p.ClfTime,
+		p.Rfc1123Time,
+		p.SyslogTime,
+		p.PythonTime,
+		p.EpochTimeIn(opts.epochUnit),
+	)
+}
+
+// filterLine finds the timestamps in the line and annotates them with the time in the output format, e.g.
+// `1698603564 [2023-10-29T18:19:24+00:00]`. If replace is true, the timestamps are replaced instead. The rest of
+// the line is not changed. The timestamps must start and end at the word boundaries, so the numbers inside
+// the identifiers are left alone.
+func filterLine(line string, parser p.Parser, opts options, replace bool) string {
+	out := strings.Builder{}
+	cur := p.NewCursor(line)
+	// afterWord is true inside a word, where the timestamps cannot start.
+	afterWord := false
+	for !cur.Ended() {
+		if !afterWord {
+			if t, rest, ok := filterMatch(cur, parser, opts); ok {
+				matched := line[cur.Pos:rest.Pos]
+				formatted, _ := formatResult(t, opts)
+				switch {
+				case replace:
+					out.WriteString(formatted)
+				case formatted == matched:
+					out.WriteString(matched)
+				default:
+					fmt.Fprintf(&out, "%s [%s]", matched, formatted)
+				}
+				cur = rest
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(cur.String())
+		out.WriteRune(r)
+		cur = cur.Advance(size)
+		afterWord = isWordRune(r)
+	}
+	return out.String()
+}
+
+// filterMatch returns the timestamp starting at the cursor, if it ends at the word boundary.
+func filterMatch(cur p.Cursor, parser p.Parser, opts options) (p.IsoTimeNode, p.Cursor, bool) {
+	node, rest, err := parser.Parse(cur)
+	if err != nil || node == nil {
+		return p.IsoTimeNode{}, cur, false
+	}
+	if next, _ := utf8.DecodeRuneInString(rest.String()); !rest.Ended() && isWordRune(next) {
+		return p.IsoTimeNode{}, cur, false
+	}
+	var t p.IsoTimeNode
+	switch n := node.(type) {
+	case p.IsoTimeNode:
+		t = n
+	case p.PartialTimeNode:
+		t = n.Resolve(nowFunc(), opts.loc)
+	case p.EpochTimeNode:
+		t = n.ToIsoTimeNode()
+		if t.Time.Before(filterEpochMin) || !t.Time.Before(filterEpochMax) {
+			return p.IsoTimeNode{}, cur, false
+		}
+	default:
+		return p.IsoTimeNode{}, cur, false
+	}
+	return t, rest, true
+}
+
+// isWordRune reports if the rune is a part of a word or a number.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// runFilter copies the input to the output with the timestamps annotated or replaced.
+func runFilter(r io.Reader, w io.Writer, opts options, replace bool) error {
+	parser := filterParser(opts)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fmt.Fprintln(w, filterLine(scanner.Text(), parser, opts, replace))
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterLine(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 11, 15, 13, 14, 15, 0, time.UTC)
+	}
+	parser := filterParser(defaultOptions)
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"at 1698603564 done", "at 1698603564 [2023-10-29T18:19:24+00:00] done"},
+		{"ts=1698603564123.", "ts=1698603564123 [2023-10-29T18:19:24.123+00:00]."},
+		{"id=a1698603564 x1698603564y", "id=a1698603564 x1698603564y"},
+		{"pid 4242 port 8080 v1.2.3 took 1.5s", "pid 4242 port 8080 v1.2.3 took 1.5s"},
+		{"2023-10-29T18:19:24Z ok", "2023-10-29T18:19:24Z [2023-10-29T18:19:24+00:00] ok"},
+		{"already 2023-10-29T18:19:24+00:00", "already 2023-10-29T18:19:24+00:00"},
+		{`[29/Oct/2023:19:40:09 +0000] "GET / HTTP/1.1" 200`, `[29/Oct/2023:19:40:09 +0000] [2023-10-29T19:40:09+00:00] "GET / HTTP/1.1" 200`},
+		{"Oct 29 19:40:09 host sshd[123]: zażółć", "Oct 29 19:40:09 [2023-10-29T19:40:09+00:00] host sshd[123]: zażółć"},
+		{"Date: Sun, 29 Oct 2023 19:40:09 GMT", "Date: Sun, 29 Oct 2023 19:40:09 GMT [2023-10-29T19:40:09+00:00]"},
+		{"", ""},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, filterLine(tc.input, parser, defaultOptions, false))
+		})
+	}
+}
+
+func TestFilterReplace(t *testing.T) {
+	opts := defaultOptions
+	opts.loc, _ = loadLocation("Europe/Warsaw")
+	opts.timeFormat, _ = parseTimeFormat("%H:%M:%S")
+	out := bytes.Buffer{}
+	err := runFilter(strings.NewReader("at 1698603564 and 2023-10-29T18:19:25Z\nno timestamps 42\n"), &out, opts, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "at 19:19:24 and 19:19:25\nno timestamps 42\n", out.String())
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFormat renders the timestamp. The timestamp is already in the location it should be printed in.
+type timeFormat func(time.Time) string
+
+func layoutFormat(layout string) timeFormat {
+	return func(t time.Time) string {
+		return t.Format(layout)
+	}
+}
+
+func epochFormat(unit p.EpochUnit) timeFormat {
+	return func(t time.Time) string {
+		return formatEpoch(t, unit)
+	}
+}
+
+// timeFormats are the named output formats of the timestamps.
+var timeFormats = map[string]timeFormat{
+	"iso": func(t time.Time) string {
+		return fmt.Sprint(p.IsoTimeNode{Time: t, Loc: t.Location()})
+	},
+	"rfc3339nano": layoutFormat(time.RFC3339Nano),
+	"rfc1123":     layoutFormat(time.RFC1123),
+	"rfc1123z":    layoutFormat(time.RFC1123Z),
+	"syslog":      layoutFormat(time.Stamp),
+	"date":        layoutFormat("2006-01-02"),
+	"epoch":       epochFormat(p.EpochSeconds),
+	"epoch-ms":    epochFormat(p.EpochMillis),
+	"epoch-us":    epochFormat(p.EpochMicros),
+	"epoch-ns":    epochFormat(p.EpochNanos),
+	"relative":    formatRelative,
+}
+
+func timeFormatNames() string {
+	names := []string{}
+	for name := range timeFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseTimeFormat returns the format for its name, or for the strftime-style layout like "%Y-%m-%d %H:%M".
+func parseTimeFormat(spec string) (timeFormat, error) {
+	if f, ok := timeFormats[strings.ToLower(spec)]; ok {
+		return f, nil
+	}
+	if strings.Contains(spec, "%") {
+		return compileStrftime(spec)
+	}
+	return nil, fmt.Errorf("unknown format %q, expected one of %s, or a strftime layout like %%Y-%%m-%%d", spec, timeFormatNames())
+}
+
+// formatEpoch prints the timestamp as the epoch number in the unit. The fractional part is printed only if it is
+// significant.
+func formatEpoch(t time.Time, unit p.EpochUnit) string {
+	nsec := new(big.Int).Mul(big.NewInt(t.Unix()), big.NewInt(1_000_000_000))
+	nsec.Add(nsec, big.NewInt(int64(t.Nanosecond())))
+	sign := ""
+	if nsec.Sign() < 0 {
+		sign = "-"
+		nsec.Neg(nsec)
+	}
+	// The number of digits after the decimal point of the unit.
+	fracDigits := 9 - unit.SubSecondDigits()
+	digits := nsec.String()
+	if len(digits) <= fracDigits {
+		digits = strings.Repeat("0", fracDigits-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-fracDigits], strings.TrimRight(digits[len(digits)-fracDigits:], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// formatRelative prints the timestamp relative to now, in the largest unit and the next one, e.g. "2h13m ago" or
+// "in 5m".
+func formatRelative(t time.Time) string {
+	// The now of the expression is a moment earlier, so `now + 5m` would be 4m59s away.
+	d := t.Sub(nowFunc()).Round(time.Second)
+	abs := d
+	if abs < 0 {
+		abs = -abs
+	}
+	days, hours, minutes, seconds := splitDuration(abs)
+	values := []int64{days, hours, minutes, int64(seconds / time.Second)}
+	units := []string{"d", "h", "m", "s"}
+	text := ""
+	for i, value := range values {
+		if value == 0 {
+			continue
+		}
+		text = fmt.Sprintf("%d%s", value, units[i])
+		if i+1 < len(values) && values[i+1] != 0 {
+			text += fmt.Sprintf("%d%s", values[i+1], units[i+1])
+		}
+		break
+	}
+	switch {
+	case text == "":
+		return "now"
+	case d < 0:
+		return text + " ago"
+	}
+	return "in " + text
+}
+
+// periodFormat renders the period. The location is used to tell the anchored periods in calendar terms.
+type periodFormat func(n p.PeriodNode, loc *time.Location) (string, error)
+
+// periodFormats are the named output formats of the periods.
+var periodFormats = map[string]periodFormat{
+	"go": func(n p.PeriodNode, _ *time.Location) (string, error) {
+		return fmt.Sprint(n), nil
+	},
+	"compact": func(n p.PeriodNode, _ *time.Location) (string, error) {
+		return formatCompactPeriod(n)
+	},
+	"verbose": formatVerbosePeriod,
+	"iso": func(n p.PeriodNode, _ *time.Location) (string, error) {
+		return formatIsoPeriod(n)
+	},
+}
+
+// periodUnits are the units in which the total length of the period can be printed.
+var periodUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+func periodFormatNames() string {
+	names := []string{}
+	for name := range periodFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parsePeriodFormat returns the format for its name, or the format printing the total length of the period in
+// the unit, e.g. "h".
+func parsePeriodFormat(spec string) (periodFormat, error) {
+	if f, ok := periodFormats[strings.ToLower(spec)]; ok {
+		return f, nil
+	}
+	if unit, ok := periodUnits[spec]; ok {
+		return func(n p.PeriodNode, _ *time.Location) (string, error) {
+			if n.IsCalendar() {
+				return "", fmt.Errorf("calendar period %s does not have fixed length in %s", n, spec)
+			}
+			return fmt.Sprintf("%.2f%s", float64(n.Duration)/float64(unit), spec), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown period format %q, expected one of %s, or a unit like h", spec, periodFormatNames())
+}
+
+// splitDuration splits the duration into whole days, hours, minutes and the rest in seconds. The duration must not
+// be negative.
+func splitDuration(d time.Duration) (days, hours, minutes int64, seconds time.Duration) {
+	days = int64(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hours = int64(d / time.Hour)
+	d -= time.Duration(hours) * time.Hour
+	minutes = int64(d / time.Minute)
+	d -= time.Duration(minutes) * time.Minute
+	return days, hours, minutes, d
+}
+
+// formatSeconds prints the seconds with the fraction only if it is significant.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// negativePeriod returns the absolute value of the period and the sign to print in front of it. The periods with
+// mixed signs, like 1mo-1d, are returned as they are.
+func negativePeriod(n p.PeriodNode) (p.PeriodNode, string) {
+	if n.Months <= 0 && n.Days <= 0 && n.Duration <= 0 && (n.Months != 0 || n.Days != 0 || n.Duration != 0) {
+		return n.Neg(), "-"
+	}
+	return n, ""
+}
+
+// hasMixedSigns reports if the parts of the period have different signs, like 1mo-1d, so the period cannot be
+// printed with a single sign in front of it.
+func hasMixedSigns(n p.PeriodNode) bool {
+	positive := n.Months > 0 || n.Days > 0 || n.Duration > 0
+	negative := n.Months < 0 || n.Days < 0 || n.Duration < 0
+	return positive && negative
+}
+
+// formatCompactPeriod prints the period with days, e.g. "396d 1h 18m 7s".
+func formatCompactPeriod(n p.PeriodNode) (string, error) {
+	if hasMixedSigns(n) {
+		return "", fmt.Errorf("cannot print period %s with mixed signs in compact format", n)
+	}
+	n, sign := negativePeriod(n)
+	parts := []string{}
+	if years := n.Months / 12; years != 0 {
+		parts = append(parts, fmt.Sprintf("%dy", years))
+	}
+	if months := n.Months % 12; months != 0 {
+		parts = append(parts, fmt.Sprintf("%dmo", months))
+	}
+	days, hours, minutes, seconds := splitDuration(n.Duration)
+	if days += int64(n.Days); days != 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours != 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes != 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	if seconds != 0 || len(parts) == 0 {
+		parts = append(parts, formatSeconds(seconds)+"s")
+	}
+	return sign + strings.Join(parts, " "), nil
+}
+
+// formatIsoPeriod prints the period as ISO 8601 duration, e.g. "P396DT1H18M7S". The negative periods are prefixed
+// with minus sign, which is a common extension of the standard.
+func formatIsoPeriod(n p.PeriodNode) (string, error) {
+	if hasMixedSigns(n) {
+		return "", fmt.Errorf("cannot print period %s with mixed signs as ISO 8601 duration", n)
+	}
+	n, sign := negativePeriod(n)
+	b := strings.Builder{}
+	b.WriteString(sign + "P")
+	if years := n.Months / 12; years != 0 {
+		fmt.Fprintf(&b, "%dY", years)
+	}
+	if months := n.Months % 12; months != 0 {
+		fmt.Fprintf(&b, "%dM", months)
+	}
+	days, hours, minutes, seconds := splitDuration(n.Duration)
+	if days += int64(n.Days); days != 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if hours != 0 || minutes != 0 || seconds != 0 {
+		b.WriteString("T")
+		if hours != 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+		if minutes != 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+		if seconds != 0 {
+			fmt.Fprintf(&b, "%sS", formatSeconds(seconds))
+		}
+	}
+	if b.Len() == len(sign)+1 {
+		b.WriteString("T0S")
+	}
+	return b.String(), nil
+}
+
+// formatVerbosePeriod prints the period in English, e.g. "1 year, 1 month, 2 hours". If the period is the
+// difference of two timestamps, then the years, months and days are counted on the calendar between them.
+func formatVerbosePeriod(n p.PeriodNode, loc *time.Location) (string, error) {
+	if n.IsAnchored() && !n.IsCalendar() {
+		n = calendarPeriod(n.Since.In(loc), n.Since.Add(n.Duration).In(loc))
+	}
+	if hasMixedSigns(n) {
+		return "", fmt.Errorf("cannot print period %s with mixed signs in words", n)
+	}
+	n, sign := negativePeriod(n)
+	days, hours, minutes, seconds := splitDuration(n.Duration)
+	parts := []string{}
+	for _, c := range []struct {
+		value int64
+		unit  string
+	}{
+		{int64(n.Months / 12), "year"},
+		{int64(n.Months % 12), "month"},
+		{days + int64(n.Days), "day"},
+		{hours, "hour"},
+		{minutes, "minute"},
+	} {
+		if c.value != 0 {
+			parts = append(parts, plural(formatInt(c.value), c.unit))
+		}
+	}
+	if seconds != 0 || len(parts) == 0 {
+		parts = append(parts, plural(formatSeconds(seconds), "second"))
+	}
+	if sign != "" {
+		sign = "minus "
+	}
+	return sign + strings.Join(parts, ", "), nil
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func plural(value, unit string) string {
+	if value == "1" {
+		return value + " " + unit
+	}
+	return value + " " + unit + "s"
+}
+
+// calendarPeriod returns the period between two times counted in whole months and days on the calendar, and the
+// fixed duration for the rest.
+func calendarPeriod(from, to time.Time) p.PeriodNode {
+	if to.Before(from) {
+		return calendarPeriod(to, from).Neg()
+	}
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	for months > 0 && from.AddDate(0, months, 0).After(to) {
+		months--
+	}
+	days := 0
+	for !from.AddDate(0, months, days+1).After(to) {
+		days++
+	}
+	return p.PeriodNode{
+		Months:   months,
+		Days:     days,
+		Duration: to.Sub(from.AddDate(0, months, days)),
+	}
+}
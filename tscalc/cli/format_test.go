@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	p "lib/tscalc/parse"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEpoch(t *testing.T) {
+	for _, tc := range []struct {
+		t        time.Time
+		unit     p.EpochUnit
+		expected string
+	}{
+		{time.Unix(1698603564, 0), p.EpochSeconds, "1698603564"},
+		{time.Unix(1698603564, 0), p.EpochMillis, "1698603564000"},
+		{time.Unix(1698603564, 123_456_789), p.EpochMillis, "1698603564123.456789"},
+		{time.Unix(1698603564, 123_456_789), p.EpochMicros, "1698603564123456.789"},
+		{time.Unix(1698603564, 123_456_789), p.EpochNanos, "1698603564123456789"},
+		{time.Unix(0, 5), p.EpochSeconds, "0.000000005"},
+		{time.Unix(-1, 500_000_000), p.EpochSeconds, "-0.5"},
+		{time.Unix(-1, 500_000_000), p.EpochMillis, "-500"},
+	} {
+		t.Run(fmt.Sprintf("%s in %s", tc.expected, tc.unit), func(t *testing.T) {
+			assert.Equal(t, tc.expected, formatEpoch(tc.t, tc.unit))
+		})
+	}
+}
+
+func TestTimeFormats(t *testing.T) {
+	ts := time.Date(2023, 10, 29, 19, 40, 9, 123_000_000, time.UTC)
+	for _, tc := range []struct {
+		format   string
+		expected string
+	}{
+		{"iso", "2023-10-29T19:40:09.123+00:00"},
+		{"rfc3339nano", "2023-10-29T19:40:09.123Z"},
+		{"rfc1123", "Sun, 29 Oct 2023 19:40:09 UTC"},
+		{"rfc1123z", "Sun, 29 Oct 2023 19:40:09 +0000"},
+		{"syslog", "Oct 29 19:40:09"},
+		{"date", "2023-10-29"},
+		{"epoch", "1698608409.123"},
+		{"epoch-ms", "1698608409123"},
+		{"EPOCH-NS", "1698608409123000000"},
+		{"%Y-%m-%d %H:%M:%S", "2023-10-29 19:40:09"},
+		{"%d/%b/%Y:%T %z", "29/Oct/2023:19:40:09 +0000"},
+		{"%a %A %B %e %I%p", "Sun Sunday October 29 07PM"},
+		{"%j %u %w %V %G", "302 7 0 43 2023"},
+		{"%s.%f %N %%", "1698608409.123000 123000000 %"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			f, err := parseTimeFormat(tc.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, f(ts))
+		})
+	}
+}
+
+func TestTimeFormatErrors(t *testing.T) {
+	for _, spec := range []string{"unknown", "%Y-%Q", "%Y%"} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := parseTimeFormat(spec)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRelativeFormat(t *testing.T) {
+	now := time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return now
+	}
+	for _, tc := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "now"},
+		{-(2*time.Hour + 13*time.Minute + 20*time.Second), "2h13m ago"},
+		{5*time.Minute - time.Millisecond, "in 5m"},
+		{-45 * time.Second, "45s ago"},
+		{26*time.Hour + 5*time.Second, "in 1d2h"},
+		{-(3*24*time.Hour + 10*time.Minute), "3d ago"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, formatRelative(now.Add(tc.d)))
+		})
+	}
+}
+
+func TestPeriodFormats(t *testing.T) {
+	long := p.PeriodNode{Duration: 9505*time.Hour + 18*time.Minute + 7*time.Second}
+	for _, tc := range []struct {
+		format   string
+		period   p.PeriodNode
+		expected string
+	}{
+		{"go", long, "9505h18m7s"},
+		{"compact", long, "396d 1h 18m 7s"},
+		{"compact", p.PeriodNode{Months: 13, Days: 2, Duration: 1500 * time.Millisecond}, "1y 1mo 2d 1.5s"},
+		{"compact", p.PeriodNode{Duration: -90 * time.Minute}, "-1h 30m"},
+		{"compact", p.PeriodNode{}, "0s"},
+		{"iso", long, "P396DT1H18M7S"},
+		{"iso", p.PeriodNode{Months: 14, Days: 3, Duration: 1500 * time.Millisecond}, "P1Y2M3DT1.5S"},
+		{"iso", p.PeriodNode{Days: -1}, "-P1D"},
+		{"iso", p.PeriodNode{}, "PT0S"},
+		{"verbose", long, "396 days, 1 hour, 18 minutes, 7 seconds"},
+		{"verbose", p.PeriodNode{Months: 1, Days: 1, Duration: 2 * time.Second}, "1 month, 1 day, 2 seconds"},
+		{"verbose", p.PeriodNode{Duration: -time.Minute}, "minus 1 minute"},
+		{"verbose", p.PeriodNode{}, "0 seconds"},
+		{"h", long, "9505.30h"},
+		{"s", p.PeriodNode{Duration: 1500 * time.Millisecond}, "1.50s"},
+		{"d", p.PeriodNode{Duration: 36 * time.Hour}, "1.50d"},
+	} {
+		t.Run(fmt.Sprintf("%s as %s", tc.period, tc.format), func(t *testing.T) {
+			f, err := parsePeriodFormat(tc.format)
+			assert.NoError(t, err)
+			actual, err := f(tc.period, time.UTC)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestVerboseAnchoredPeriod(t *testing.T) {
+	since := time.Date(2022, 9, 28, 18, 22, 32, 0, time.UTC)
+	until := time.Date(2023, 10, 29, 19, 40, 39, 0, time.UTC)
+	f, err := parsePeriodFormat("verbose")
+	assert.NoError(t, err)
+	actual, err := f(p.PeriodNode{Duration: until.Sub(since), Since: since}, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 year, 1 month, 1 day, 1 hour, 18 minutes, 7 seconds", actual)
+	actual, err = f(p.PeriodNode{Duration: since.Sub(until), Since: until}, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "minus 1 year, 1 month, 1 day, 1 hour, 18 minutes, 7 seconds", actual)
+}
+
+func TestCalendarPeriodInUnitError(t *testing.T) {
+	f, err := parsePeriodFormat("h")
+	assert.NoError(t, err)
+	_, err = f(p.PeriodNode{Months: 1}, time.UTC)
+	assert.Error(t, err)
+}
+
+func TestMixedSignsPeriodError(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		period   p.PeriodNode
+		expected string
+	}{
+		{"iso", p.PeriodNode{Months: 1, Days: -1}, "cannot print period 1mo-1d with mixed signs as ISO 8601 duration"},
+		{"compact", p.PeriodNode{Months: 1, Duration: -time.Hour}, "cannot print period 1mo-1h0m0s with mixed signs in compact format"},
+		{"verbose", p.PeriodNode{Months: 1, Days: -1}, "cannot print period 1mo-1d with mixed signs in words"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			f, err := parsePeriodFormat(tc.format)
+			assert.NoError(t, err)
+			_, err = f(tc.period, time.UTC)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
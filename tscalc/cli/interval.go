@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"time"
+)
+
+const (
+	strRange     = ".."
+	strLess      = "<"
+	strLessEq    = "<="
+	strGreater   = ">"
+	strGreaterEq = ">="
+	strEqual     = "=="
+	strNotEqual  = "!="
+	strIn        = "in"
+	strOverlaps  = "overlaps"
+	strUnion     = "union"
+	strIntersect = "intersect"
+)
+
+// intervalNode is the closed interval between two timestamps, e.g. `now-2h .. now`.
+type intervalNode struct {
+	start time.Time
+	end   time.Time
+	cur   p.Cursor
+}
+
+func (n intervalNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n intervalNode) String() string {
+	return fmt.Sprintf("%s .. %s", p.IsoTimeNode{Time: n.start}, p.IsoTimeNode{Time: n.end})
+}
+
+func (n intervalNode) contains(t time.Time) bool {
+	return !t.Before(n.start) && !t.After(n.end)
+}
+
+func (n intervalNode) overlaps(other intervalNode) bool {
+	return !n.start.After(other.end) && !other.start.After(n.end)
+}
+
+// boolNode is the result of a comparison.
+type boolNode struct {
+	value bool
+	cur   p.Cursor
+}
+
+func (n boolNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n boolNode) String() string {
+	return fmt.Sprint(n.value)
+}
+
+// lengthNode is the length of the interval, e.g. `length(start of day .. now)`.
+type lengthNode struct {
+	node p.Node
+	cur  p.Cursor
+}
+
+func (n lengthNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n lengthNode) String() string {
+	return fmt.Sprintf("length(%s)", n.node)
+}
+
+func buildLength(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	if seq.Len() != 3 {
+		return nil, cursorError{err: fmt.Errorf("missing closing parenthesis"), cur: seq.Cursor()}
+	}
+	return lengthNode{node: seq.Nodes[1], cur: seq.Cursor()}, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case strLess, strLessEq, strGreater, strGreaterEq, strEqual, strNotEqual:
+		return true
+	}
+	return false
+}
+
+func isIntervalOp(op string) bool {
+	switch op {
+	case strIn, strOverlaps, strUnion, strIntersect:
+		return true
+	}
+	return false
+}
+
+// newInterval returns the interval between two timestamps. The end must not be before the start.
+func newInterval(start, end p.IsoTimeNode) (intervalNode, error) {
+	if end.Time.Before(start.Time) {
+		return intervalNode{}, fmt.Errorf("interval ends at %s before it starts at %s", end, start)
+	}
+	return intervalNode{start: start.Time, end: end.Time, cur: start.Cursor()}, nil
+}
+
+// intervalLength returns the length of the interval as the period anchored at its start.
+func intervalLength(n intervalNode) p.PeriodNode {
+	return p.PeriodNode{Duration: n.end.Sub(n.start), Since: n.start, Cur: n.cur}
+}
+
+// compare compares two timestamps, periods or numbers. The intervals and booleans can be only tested for equality.
+func compare(left p.Node, op string, right p.Node) (boolNode, error) {
+	// c is -1, 0 or 1, like in strings.Compare.
+	var c int
+	switch l := left.(type) {
+	case p.IsoTimeNode:
+		r, ok := right.(p.IsoTimeNode)
+		if !ok {
+			return boolNode{}, operationError(left, op, right)
+		}
+		c = compareOrdered(l.Time.Sub(r.Time), 0)
+	case p.PeriodNode:
+		r, ok := right.(p.PeriodNode)
+		if !ok {
+			return boolNode{}, operationError(left, op, right)
+		}
+		if l.IsCalendar() || r.IsCalendar() {
+			return boolNode{}, fmt.Errorf("cannot compare calendar periods %s and %s", l, r)
+		}
+		c = compareOrdered(l.Duration, r.Duration)
+	case scalarNode:
+		r, ok := right.(scalarNode)
+		if !ok {
+			return boolNode{}, operationError(left, op, right)
+		}
+		c = compareOrdered(l.value, r.value)
+	case intervalNode, boolNode:
+		if typeName(left) != typeName(right) || (op != strEqual && op != strNotEqual) {
+			return boolNode{}, operationError(left, op, right)
+		}
+		// The intervals have the time.Time fields, which must not be compared with ==.
+		equal := fmt.Sprint(left) == fmt.Sprint(right)
+		return boolNode{value: equal == (op == strEqual), cur: right.Cursor()}, nil
+	default:
+		return boolNode{}, operationError(left, op, right)
+	}
+	value := map[string]bool{
+		strLess:      c < 0,
+		strLessEq:    c <= 0,
+		strGreater:   c > 0,
+		strGreaterEq: c >= 0,
+		strEqual:     c == 0,
+		strNotEqual:  c != 0,
+	}[op]
+	return boolNode{value: value, cur: right.Cursor()}, nil
+}
+
+func compareOrdered[T time.Duration | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// combineIntervals performs the operations on intervals: `in` tests if the timestamp or the interval is inside
+// the interval, `overlaps` tests if two intervals have a common part, `union` and `intersect` return the interval.
+func combineIntervals(left p.Node, op string, right p.Node) (p.Node, error) {
+	r, ok := right.(intervalNode)
+	if !ok {
+		return nil, operationError(left, op, right)
+	}
+	switch l := left.(type) {
+	case p.IsoTimeNode:
+		if op == strIn {
+			return boolNode{value: r.contains(l.Time), cur: r.Cursor()}, nil
+		}
+	case intervalNode:
+		switch op {
+		case strIn:
+			return boolNode{value: r.contains(l.start) && r.contains(l.end), cur: r.Cursor()}, nil
+		case strOverlaps:
+			return boolNode{value: l.overlaps(r), cur: r.Cursor()}, nil
+		case strUnion, strIntersect:
+			if !l.overlaps(r) {
+				return nil, fmt.Errorf("intervals %s and %s do not overlap", l, r)
+			}
+			if op == strUnion {
+				return intervalNode{start: earlier(l.start, r.start), end: later(l.end, r.end), cur: l.cur}, nil
+			}
+			return intervalNode{start: later(l.start, r.start), end: earlier(l.end, r.end), cur: l.cur}, nil
+		}
+	}
+	return nil, operationError(left, op, right)
+}
+
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
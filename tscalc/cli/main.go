@@ -1,126 +1,375 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
 	p "lib/tscalc/parse"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata"
 )
 
 var nowFunc func() time.Time = time.Now
 
+// options are the settings, set from the command line flags, that affect how the results are printed.
+type options struct {
+	// loc is the location in which the timestamps are printed.
+	loc *time.Location
+	// zoneSuffix is true if the location is set with `in <zone>` at the end of the line, so a single ISO timestamp
+	// at the input is printed in that zone rather than converted to epoch seconds.
+	zoneSuffix bool
+	// epochUnit is the unit of the epoch timestamps at the input.
+	epochUnit p.EpochUnit
+	// timeFormat is the format of the timestamps at the output. If nil, the timestamps are printed in ISO format,
+	// and a single ISO timestamp at the input is converted to epoch seconds.
+	timeFormat timeFormat
+	// periodFormat is the format of the periods at the output. If nil, the periods are printed like Go durations.
+	periodFormat periodFormat
+	// snowflakeEpoch is the epoch of the snowflake identifiers at the input. If zero, the snowflakes are not
+	// recognized, as they look like the epoch timestamps.
+	snowflakeEpoch time.Time
+	// pairs prints the sequences of timestamps as the start and the end of each step, separated by a tab.
+	pairs bool
+}
+
+var defaultOptions = options{loc: time.UTC}
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: Paste the timestamp or operations on timestamp at the input. If there is no operation, the timestamp will be converted between epoch seconds and UTC time.\n\n`)
+		fmt.Fprintf(os.Stderr, `Usage: Paste the timestamp or operations on timestamp at the input, or give them as the arguments. If there is no operation, the timestamp will be converted between epoch seconds and UTC time.\n\n`)
 		flag.PrintDefaults()
 	}
 	var verbose bool
+	var tz string
+	var epochUnit string
+	var outputFormat string
+	var periodOutputFormat string
+	var pairs bool
+	var interactive bool
+	var filter bool
+	var replace bool
+	var expr string
+	var delim string
+	var header bool
+	var only bool
+	var statsMode bool
+	var buckets string
+	var gaps string
+	var rate string
+	var sortEvents bool
+	var column int
+	var workweek string
+	var workhours string
+	var holidays string
+	var snowflake string
+	var zonesSpec string
+	var describe bool
+	var relative bool
 	flag.BoolVar(&verbose, "v", false, "verbose")
+	flag.StringVar(&tz, "tz", "UTC", "time zone in which the timestamps are printed, e.g. Europe/Warsaw or local. Can be overriden per line with \"in <zone>\" suffix.")
+	flag.StringVar(&epochUnit, "epoch-unit", "auto", "unit of the epoch timestamps at the input: s, ms, us, ns, or auto to detect the unit from the number of digits")
+	flag.StringVar(&outputFormat, "o", "", fmt.Sprintf("output format of the timestamps: %s, or a strftime layout like %%Y-%%m-%%d. Can be overriden per line with \"as <format>\" suffix.", timeFormatNames()))
+	flag.StringVar(&periodOutputFormat, "p", "", fmt.Sprintf("output format of the periods: %s, or a unit like s or h to print the total length in that unit. Can be overriden per line with \"as <format>\" or \"in <unit>\" suffix.", periodFormatNames()))
+	flag.BoolVar(&pairs, "pairs", false, "print the sequences from \"every <period> from <start> to <end>\" as start<TAB>end pairs")
+	flag.BoolVar(&interactive, "i", false, "interactive mode with line editing and history when the input is a terminal")
+	flag.BoolVar(&filter, "filter", false, "copy the input to the output and annotate the timestamps found in the text with the time in the output format")
+	flag.BoolVar(&replace, "replace", false, "with -filter, replace the timestamps instead of annotating them")
+	flag.StringVar(&expr, "e", "", "evaluate the expression for every row of the delimited input, with the columns referenced as $1, $2..., and append the result to the row")
+	flag.StringVar(&delim, "d", `\t`, "with -e or -f, the delimiter of the columns, e.g. , for CSV")
+	flag.BoolVar(&header, "header", false, "with -e, the first row is the header and is preserved")
+	flag.BoolVar(&only, "only", false, "with -e, print only the result instead of appending it to the row")
+	flag.BoolVar(&statsMode, "stats", false, "read one period per line, e.g. a difference of timestamps, and print count, min, max, mean, stddev, percentiles and histogram")
+	flag.StringVar(&buckets, "buckets", "10", "with -stats, the number of the histogram buckets, or their width like 100ms")
+	flag.StringVar(&gaps, "gaps", "", "read one timestamp per line and print the gaps between them longer than the period, e.g. 5m")
+	flag.StringVar(&rate, "rate", "", "read one timestamp per line and print the number of them in every period, e.g. 1m or 1d")
+	flag.BoolVar(&sortEvents, "sort", false, "with -gaps or -rate, sort the timestamps instead of reporting the ones out of order")
+	flag.IntVar(&column, "f", 0, "with -gaps or -rate, the number of the column with the timestamp, separated by -d")
+	flag.StringVar(&workweek, "workweek", "Mon-Fri", "business days of the week for the bd and bh units, e.g. Sun-Thu or Mon,Wed,Fri")
+	flag.StringVar(&workhours, "workhours", "09:00-17:00", "working hours of the business days for the bh unit, in the time zone set with -tz")
+	flag.StringVar(&holidays, "holidays", "", "file with the holidays that are not business days, either iCalendar (ICS) or one date like 2023-12-25 per line")
+	flag.StringVar(&snowflake, "snowflake", "", "parse the numbers of 15 to 20 digits as snowflake identifiers with the epoch: twitter, discord, or epoch milliseconds")
+	flag.StringVar(&zonesSpec, "zones", "", "print the timestamps as a table of the time in every zone, e.g. UTC,America/New_York,Asia/Tokyo, or @file with the zones one per line")
+	flag.BoolVar(&describe, "describe", false, "print the calendar facts about the timestamps: the ISO week, the day of year, the quarter, the epoch, the time relative to now and the DST of the local time zone")
+	flag.BoolVar(&relative, "relative", false, "print the timestamps relative to now, e.g. \"2h13m ago\" or \"in 5m\", like -o relative")
 	flag.Parse()
 
+	defaultOptions.pairs = pairs
 	if !verbose {
 		log.SetOutput(io.Discard)
 	}
 
-	if stat, err := os.Stdin.Stat(); err == nil {
-		if (stat.Mode() & os.ModeCharDevice) != 0 {
-			// If stdin not opened, just print current time.
-			log.Println("No stdin, print current time")
-			fmt.Printf("%s\n", p.IsoTimeNode{Time: nowFunc()})
-			return
+	if loc, err := loadLocation(tz); err == nil {
+		defaultOptions.loc = loc
+	} else {
+		fatal(err)
+	}
+	if unit, err := p.ParseEpochUnit(epochUnit); err == nil {
+		defaultOptions.epochUnit = unit
+	} else {
+		fatal(err)
+	}
+	if snowflake != "" {
+		if epoch, err := parseSnowflakeEpoch(snowflake); err == nil {
+			defaultOptions.snowflakeEpoch = epoch
+		} else {
+			fatal(err)
+		}
+	}
+	if relative {
+		outputFormat = "relative"
+	}
+	if outputFormat != "" {
+		if f, err := parseTimeFormat(outputFormat); err == nil {
+			defaultOptions.timeFormat = f
+		} else {
+			fatal(err)
 		}
+	}
+	if periodOutputFormat != "" {
+		if f, err := parsePeriodFormat(periodOutputFormat); err == nil {
+			defaultOptions.periodFormat = f
+		} else {
+			fatal(err)
+		}
+	}
+
+	if days, err := parseWorkweek(workweek); err == nil {
+		calendar.weekdays = days
 	} else {
-		log.Fatal(err)
+		fatal(err)
+	}
+	if start, end, err := parseWorkhours(workhours); err == nil {
+		calendar.start, calendar.end = start, end
+	} else {
+		fatal(err)
+	}
+	if holidays != "" {
+		if dates, err := loadHolidays(holidays); err == nil {
+			calendar.holidays = dates
+		} else {
+			fatal(err)
+		}
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		text := scanner.Text()
-		res, err := handleLine(text)
+	if filter {
+		if err := runFilter(os.Stdin, os.Stdout, defaultOptions, replace); err != nil {
+			fatal(err)
+		}
+		return
+	}
 
+	if statsMode {
+		spec, err := parseBuckets(buckets)
 		if err != nil {
-			fmt.Println(err)
-			if nerr, ok := err.(p.CursorError); ok {
-				fmt.Println(nerr.Cursor().Input)
-				r := nerr.Cursor().Pos - 1
-				if r <= 0 {
-					r = 0
+			fatal(err)
+		}
+		failed, err := runStats(os.Stdin, os.Stdout, os.Stderr, spec)
+		if err != nil {
+			fatal(err)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if gaps != "" || rate != "" {
+		ev := events{sort: sortEvents, column: column}
+		var err error
+		if gaps != "" {
+			if ev.gaps, err = parseGaps(gaps); err != nil {
+				fatal(err)
+			}
+		}
+		if rate != "" {
+			if ev.rate, err = parseRate(rate); err != nil {
+				fatal(err)
+			}
+		}
+		if ev.delim, err = parseDelimiter(delim); err != nil {
+			fatal(err)
+		}
+		failed, err := ev.run(os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			fatal(err)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if expr != "" {
+		d, err := parseDelimiter(delim)
+		if err != nil {
+			fatal(err)
+		}
+		c := columnar{expr: expr, delim: d, header: header, only: only}
+		failed, err := c.run(os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			printError(os.Stderr, err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The variables are kept between the lines, so a script can be piped to the input.
+	s := newSession()
+	if zonesSpec != "" {
+		zones, err := parseZones(zonesSpec)
+		if err != nil {
+			fatal(err)
+		}
+		s.zones = zones
+	}
+	s.describe = describe
+
+	// The arguments are the line, e.g. `tscalc now + 1h`.
+	if flag.NArg() > 0 {
+		result, err := s.printLine(os.Stdout, strings.Join(flag.Args(), " "))
+		if b, ok := result.(boolNode); err != nil || ok && !b.value {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if stat, err := os.Stdin.Stat(); err == nil {
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			if interactive {
+				if err := s.runInteractive(); err != nil {
+					fatal(err)
 				}
-				fmt.Printf("%s^\n", strings.Repeat("_", r))
-				os.Exit(1)
+				return
 			}
+			// If stdin not opened, just print current time.
+			log.Println("No stdin, print current time")
+			res, _ := s.format(p.IsoTimeNode{Time: nowFunc()}, defaultOptions)
+			fmt.Println(res)
+			return
 		}
-		fmt.Println(res)
+	} else {
+		fatal(err)
+	}
+
+	ok, err := s.run(os.Stdin, os.Stdout)
+	if err != nil {
+		fatal(err)
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("error: %v", err)
+	if !ok {
+		os.Exit(1)
 	}
 }
 
+// handleLine evaluates a single line without the variables of the other lines.
 func handleLine(line string) (string, error) {
-	line = strings.TrimSpace(line)
-	root, err := parseInput(line)
+	return newSession().handleLine(line)
+}
+
+func (s *session) handleLine(line string) (string, error) {
+	result, opts, err := s.evalLine(line)
 	if err != nil {
 		return "", err
 	}
+	return s.format(result, opts)
+}
 
-	// If there is a single element at the input, just convert the format.
-	if seq, ok := root.(p.SequenceNode); ok {
-		if nonEmpty := seq.RemoveEmpty(); nonEmpty.Len() == 1 {
-			switch n := nonEmpty.Nodes[0].(type) {
-			case p.EpochTimeNode:
-				return fmt.Sprint(n.ToIsoTimeNode()), nil
-			case p.IsoTimeNode:
-				return fmt.Sprint(n.ToEpochTimeNode()), nil
-			case p.LiteralNode:
-				if n.Literal == strNow {
-					return fmt.Sprint(p.IsoTimeNode{Time: nowFunc()}), nil
-				}
-			}
-		}
+// evalLine returns the result of the line and the options, overridden by the suffixes of the line, in which it
+// should be printed. The result is stored as `_` variable.
+func (s *session) evalLine(line string) (p.Node, options, error) {
+	result, opts, err := s.evalExpr(line)
+	if err == nil {
+		s.vars[strLast] = result
 	}
+	return result, opts, err
+}
 
-	seq := root.(p.SequenceNode)
-	if seq.Len() != 2 {
-		return "", fmt.Errorf("expected sequence of 2 elements, got: %s", seq)
+func (s *session) evalExpr(line string) (p.Node, options, error) {
+	root, opts, err := parseLine(line, defaultOptions, s.names())
+	if err != nil {
+		return nil, opts, err
 	}
 
-	acc := seq.Nodes[0]
-	// Initial acc can be either term or [+=] period (a sequence). Here make it a single term.
-	if seq, ok := acc.(p.SequenceNode); ok {
-		if seq.Len() == 2 {
-			literal := seq.Nodes[0].(p.LiteralNode)
-			if literal.Literal == strMinus {
-				acc = p.PeriodNode{Duration: -1 * seq.Nodes[1].(p.PeriodNode).Duration}
-			} else {
-				acc = seq.Nodes[1]
-			}
+	// If there is a single element at the input, just convert the format.
+	// The timestamp with the missing parts is converted like the complete one. The anchors like `today` are printed
+	// like `now`.
+	if n, ok := root.(p.PartialTimeNode); ok {
+		root = n.Resolve(nowFunc(), opts.loc)
+	}
+	switch n := root.(type) {
+	case p.EpochTimeNode:
+		return n.ToIsoTimeNode(), opts, nil
+	case p.IsoTimeNode:
+		// The time of the identifier, e.g. ULID, is printed like the timestamp.
+		if opts.timeFormat == nil && !opts.zoneSuffix && !p.IsIdFormat(n.Format) {
+			return n.ToEpochTimeNode(), opts, nil
 		}
+		return n, opts, nil
 	}
 
-	reduced, err := reduce(acc, seq.Nodes[1], nowFunc())
-
 	// When at the input there are more values, then perform the proper calculations.
-	//reduced, err := reduce(root, nowFunc())
+	e := evaluator{now: nowFunc(), loc: opts.loc, vars: s.vars}
+	if n, ok := root.(assignNode); ok {
+		value, err := e.eval(n.value)
+		if err != nil {
+			return nil, opts, err
+		}
+		s.vars[n.name] = value
+		return value, opts, nil
+	}
+	result, err := e.eval(root)
+	return result, opts, err
+}
+
+// parseLine returns the syntax tree of the line and the options overridden by the suffixes of the line.
+func parseLine(line string, opts options, vars []string) (p.Node, options, error) {
+	top, err := parseInput(strings.TrimSpace(line), opts, vars)
 	if err != nil {
-		return "", err
+		return nil, opts, err
+	}
+
+	topSeq := top.(p.SequenceNode).RemoveEmpty()
+	root := topSeq.Nodes[0]
+	if topSeq.Len() == 2 {
+		if opts, err = applySuffixes(opts, topSeq.Nodes[1].(p.SequenceNode)); err != nil {
+			return nil, opts, err
+		}
 	}
 
-	// Format output
-	switch n := reduced.(type) {
-	case p.IsoTimeNode, p.PeriodNode:
-		return fmt.Sprint(n), nil
+	logTerms(root)
+	return root, opts, nil
+}
+
+// formatResult prints the result of the evaluation.
+func formatResult(result p.Node, opts options) (string, error) {
+	switch n := result.(type) {
+	case p.IsoTimeNode:
+		if opts.timeFormat != nil {
+			return opts.timeFormat(n.Time.In(opts.loc)), nil
+		}
+		return fmt.Sprint(n.In(opts.loc)), nil
+	case p.PeriodNode:
+		if opts.periodFormat != nil {
+			return opts.periodFormat(n, opts.loc)
+		}
+	case intervalNode:
+		start, _ := formatResult(p.IsoTimeNode{Time: n.start}, opts)
+		end, _ := formatResult(p.IsoTimeNode{Time: n.end}, opts)
+		return start + " .. " + end, nil
+	case seriesNode:
+		return formatSeries(n, opts), nil
 	}
-	return "", fmt.Errorf("BUG! After reduction expected other node type, got %T: %v", reduced, reduced)
+	return fmt.Sprint(result), nil
 }
 
-func parseInput(input string) (p.Node, error) {
-	parser := getParser()
+func parseInput(input string, opts options, vars []string) (p.Node, error) {
+	parser := getParser(opts, vars)
 	p.Logf("parser: %s", parser)
 	root, rest, err := parser.Parse(p.NewCursor(input))
 	if err != nil {
@@ -136,132 +385,255 @@ func parseInput(input string) (p.Node, error) {
 	return root, nil
 }
 
-func getParser() p.Parser {
-	plusMinus := p.RegexGroup(`\s*([+-])\s*`)
-
-	term := p.FirstOf(
+// valueParser returns the parser of the timestamps and the periods in all the supported formats.
+func valueParser(opts options) p.Parser {
+	// The order of the terms is the precedence of the formats. The identifiers and the relative times, like `2h ago`,
+	// are before the periods, which would match only their first characters. The formats starting with digits must be before the epoch timestamps,
+	// which would match only their first number.
+	ids := []p.Parser{p.UuidTime, p.UlidTime, p.KsuidTime, p.ObjectIdTime}
+	if !opts.snowflakeEpoch.IsZero() {
+		ids = append(ids, p.SnowflakeTimeIn(opts.snowflakeEpoch))
+	}
+	return p.FirstOf(append(ids,
+		p.RelativeTime,
+		p.IsoDuration,
 		p.Period,
 		p.IsoTime,
-		p.Literal(strNow),
-		p.EpochTime,
+		p.SqlTime,
+		p.ClfTime,
+		p.Rfc1123Time,
+		p.SyslogTime,
+		p.PythonTime,
+		p.DateOnly,
+		p.TimeOnly,
+		p.Anchor,
+		// The word boundary keeps the variables starting with now, like `nowish`, from matching the literal.
+		p.Regex(strNow+`\b`),
+		p.EpochTimeIn(opts.epochUnit),
+	)...)
+}
+
+// parseSnowflakeEpoch returns the epoch of the snowflakes: twitter, discord, or the epoch milliseconds.
+func parseSnowflakeEpoch(name string) (time.Time, error) {
+	switch strings.ToLower(name) {
+	case "twitter":
+		return p.TwitterEpoch, nil
+	case "discord":
+		return p.DiscordEpoch, nil
+	}
+	ms, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown snowflake epoch %q, expected twitter, discord, or epoch milliseconds", name)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// getParser returns the parser of the expressions. The precedence, from the lowest, is: comparisons (including
+// `in` and `overlaps`), union and intersection of intervals, interval `..`, rounding (floor, ceil and round),
+// addition and subtraction, multiplication and division, unary sign, parentheses and terms. The binary operators
+// are left-associative. The whole line can be also a sequence, e.g. `every 15m from today to +6h`, or an assignment
+// to the variable, e.g. `deploy = now - 1h`. Only the names of the defined variables are recognized, so they do not
+// clash with the suffixes like `in UTC`.
+func getParser(opts options, vars []string) p.Parser {
+	term := p.FirstOf(
+		businessPeriod,
+		valueParser(opts),
+		variable(vars),
+		field,
+	)
+
+	addOp := p.RegexGroup(`\s*([+-])\s*`)
+	mulOp := p.RegexGroup(`\s*([*/])\s*`)
+	sign := p.RegexGroup(`([+-])\s*`)
+	roundOp := p.RegexGroup(`\s+(floor|ceil|round)(?:\s+to)?\s+`)
+	rangeOp := p.RegexGroup(`\s*(\.\.)\s*`)
+	setOp := p.RegexGroup(`\s+(union|intersect)\s+`)
+	compareOp := p.FirstOf(
+		p.RegexGroup(`\s*(<=|>=|==|!=|<|>)\s*`),
+		p.RegexGroup(`\s+(in|overlaps)\s+`),
 	)
-	signedTerm := p.Sequence(
-		plusMinus,
+
+	expr := p.Ref()
+	unary := p.Ref()
+	primary := p.FirstOf(
+		p.Map(
+			p.Sequence(
+				p.Regex(`\(\s*`),
+				expr,
+				p.Regex(`\s*\)`),
+			),
+			buildParens,
+		),
+		p.Map(
+			p.Sequence(
+				p.Regex(`length\(\s*`),
+				expr,
+				p.Regex(`\s*\)`),
+			),
+			buildLength,
+		),
+		p.Map(
+			p.Sequence(
+				p.Regex(`(workdays|workhours)\(\s*`),
+				expr,
+				p.Regex(`\s*\)`),
+			),
+			buildWork,
+		),
+		p.Map(
+			p.Sequence(
+				p.Regex(`cron\s+`),
+				p.Regex(`"[^"]*"|'[^']*'|@[A-Za-z]+`),
+				p.Regex(`\s+(?:next|prev)\b`),
+				p.Optional(p.RegexGroup(`\s+(\d+)\b`)),
+				p.Optional(
+					p.Sequence(
+						p.Regex(`\s+(?:after|before)\s+`),
+						unary,
+					),
+				),
+			),
+			buildCron,
+		),
+		claim,
 		term,
 	)
-	syntax := p.Sequence(
-		p.FirstOf(
+	unary.Parser = p.FirstOf(
+		p.Map(
 			p.Sequence(
-				plusMinus,
-				p.Period,
+				sign,
+				unary,
 			),
-			term,
+			buildUnary,
 		),
-		p.Optional(
-			p.Repeated(signedTerm),
+		primary,
+	)
+	product := binaryLevel(unary, mulOp, unary)
+	sum := binaryLevel(product, addOp, product)
+	rounded := binaryLevel(sum, roundOp, p.FirstOf(roundUnit, sum))
+	interval := binaryLevel(rounded, rangeOp, rounded)
+	set := binaryLevel(interval, setOp, interval)
+	expr.Parser = binaryLevel(set, compareOp, set)
+	every := p.Map(
+		p.Sequence(
+			p.Regex(`every\s+`),
+			expr,
+			p.Regex(`\s+from\s+`),
+			expr,
+			p.Regex(`\s+to\s+`),
+			expr,
+		),
+		buildEvery,
+	)
+
+	suffixes := p.Repeated(
+		p.FirstOf(
+			suffix(suffixIn, `\s+in\s+([A-Za-zµ][A-Za-z0-9_+/-]*)`),
+			suffix(suffixAs, `\s+as\s+("[^"]*"|'[^']*'|\S+)`),
 		),
 	)
-	return syntax
+	assign := p.Map(
+		p.Sequence(
+			p.RegexGroup(`([A-Za-z_][A-Za-z0-9_]*)\s*=\s*`),
+			p.FirstOf(every, expr),
+		),
+		buildAssign,
+	)
+	return p.Sequence(
+		p.FirstOf(assign, every, expr),
+		p.Optional(suffixes),
+	)
 }
 
-// reduce performs actual operations on nodes.
-func reduce(acc p.Node, seq p.Node, now time.Time) (p.Node, error) {
-	log.Printf("Reduce: %s (%T) and %s (%T)", acc, acc, seq, seq)
-	for _, opTerm := range seq.(p.SequenceNode).Nodes {
-		opTermSeq := opTerm.(p.SequenceNode)
-		if opTermSeq.Len() != 2 {
-			return nil, fmt.Errorf("expected two nodes, got %d: %s", opTermSeq.Len(), opTermSeq)
-		}
-		first := opTermSeq.Nodes[0]
-		second := opTermSeq.Nodes[1]
-		literal, ok := first.(p.LiteralNode)
-		if !ok {
-			return nil, fmt.Errorf("expected literal node, got %s (%T)", first, first)
-		}
-		combined, err := combine(acc, literal, second, nowFunc())
-		if err != nil {
-			return nil, err
-		}
-		acc = combined
-	}
-	return acc, nil
+// binaryLevel returns the parser of the left operand followed by any number of the operators with the right
+// operands, folded into the left-associative tree.
+func binaryLevel(left, op, right p.Parser) p.Parser {
+	return p.Map(
+		p.Sequence(
+			left,
+			p.Optional(
+				p.Repeated(p.Sequence(op, right)),
+			),
+		),
+		foldBinary,
+	)
 }
 
 const (
-	strPlus  = "+"
-	strMinus = "-"
-	strNow   = "now"
+	suffixIn = "in"
+	suffixAs = "as"
 )
 
-func combine(leftNode p.Node, literal p.LiteralNode, rightNode p.Node, now time.Time) (p.Node, p.CursorError) {
-	log.Printf("Combine %s (%T) %s %s (%T)", leftNode, leftNode, literal, rightNode, rightNode)
-	leftNode = forceIsoTime(leftNode, now)
-	rightNode = forceIsoTime(rightNode, now)
+// suffixNode is an option at the end of the line, like `in Europe/Warsaw`, `in h` or `as epoch`.
+type suffixNode struct {
+	key   string
+	value p.LiteralNode
+}
 
-	switch left := leftNode.(type) {
-	case p.PeriodNode:
-		switch right := rightNode.(type) {
-		case p.PeriodNode:
-			switch literal.Literal {
-			case strPlus:
-				return p.PeriodNode{
-					Duration: left.Duration + right.Duration,
-					Cur:      right.Cursor(),
-				}, nil
-			case strMinus:
-				return p.PeriodNode{
-					Duration: left.Duration - right.Duration,
-					Cur:      right.Cursor(),
-				}, nil
+func (n suffixNode) Cursor() p.Cursor {
+	return opCursor(n.value)
+}
+
+func (n suffixNode) String() string {
+	return fmt.Sprintf("%s %s", n.key, n.value.Literal)
+}
+
+func suffix(key, pattern string) p.Parser {
+	return p.Map(p.RegexGroup(pattern), func(node p.Node) (p.Node, error) {
+		return suffixNode{key: key, value: node.(p.LiteralNode)}, nil
+	})
+}
+
+// applySuffixes overrides the options with the suffixes of the line.
+func applySuffixes(opts options, suffixes p.SequenceNode) (options, error) {
+	for _, node := range suffixes.Nodes {
+		s := node.(suffixNode)
+		switch s.key {
+		case suffixIn:
+			// The unit of the period, or the time zone.
+			if _, ok := periodUnits[s.value.Literal]; ok {
+				opts.periodFormat, _ = parsePeriodFormat(s.value.Literal)
+				continue
 			}
-		case p.IsoTimeNode:
-			return p.IsoTimeNode{
-				Time: right.Time.Add(left.Duration),
-				Cur:  right.Cursor(),
-			}, nil
-		}
-	case p.IsoTimeNode:
-		switch right := rightNode.(type) {
-		case p.PeriodNode:
-			switch literal.Literal {
-			case strPlus:
-				return p.IsoTimeNode{
-					Time: left.Time.Add(right.Duration),
-					Cur:  right.Cursor(),
-				}, nil
-			case strMinus:
-				return p.IsoTimeNode{
-					Time: left.Time.Add(-1 * right.Duration),
-					Cur:  right.Cursor(),
-				}, nil
+			loc, err := loadLocation(s.value.Literal)
+			if err != nil {
+				return opts, cursorError{err: err, cur: s.Cursor()}
+			}
+			opts.loc, opts.zoneSuffix = loc, true
+		case suffixAs:
+			// The format can be for timestamps, periods, or both, like "iso".
+			spec := strings.Trim(s.value.Literal, `"'`)
+			tf, terr := parseTimeFormat(spec)
+			pf, perr := parsePeriodFormat(spec)
+			if terr != nil && perr != nil {
+				return opts, cursorError{err: terr, cur: s.Cursor()}
 			}
-		case p.IsoTimeNode:
-			switch literal.Literal {
-			case strMinus:
-				return p.PeriodNode{
-					Duration: left.Time.Sub(right.Time),
-					Cur:      right.Cur,
-				}, nil
+			if terr == nil {
+				opts.timeFormat = tf
+			}
+			if perr == nil {
+				opts.periodFormat = pf
 			}
 		}
 	}
-	err := cursorError{
-		cur: leftNode.Cursor(),
-		err: fmt.Errorf("cannot combine %s (%T) and %s and %s (%T)", leftNode, leftNode, literal, rightNode, rightNode),
-	}
-	return nil, err
+	return opts, nil
 }
 
-func forceIsoTime(node p.Node, now time.Time) p.Node {
-	switch n := node.(type) {
-	case p.EpochTimeNode:
-		return n.ToIsoTimeNode()
-	case p.LiteralNode:
-		if n.Literal == strNow {
-			return p.IsoTimeNode{Time: now, Cur: node.Cursor()}
-		}
+// loadLocation returns the location for IANA zone name. "local" stands for the local time zone of the machine.
+func loadLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "local":
+		return time.Local, nil
+	case "utc", "z":
+		return time.UTC, nil
 	}
-	return node
+	return time.LoadLocation(name)
+}
+
+// fatal prints the error and exits. The log is discarded unless -v is given, so the errors are printed to stderr.
+func fatal(err error) {
+	log.SetOutput(os.Stderr)
+	log.Fatal(err)
 }
 
 type cursorError struct {
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	p "lib/tscalc/parse"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,7 +25,7 @@ func TestCalc(t *testing.T) {
 	}{
 		{"100", "1970-01-01T00:01:40+00:00"},
 		{"  100", "1970-01-01T00:01:40+00:00"},
-		{"1970-01-01T00:01:40+00:00", "100.000000"},
+		{"1970-01-01T00:01:40+00:00", "100"},
 		{"1m + 1s", "1m1s"},
 		{"1m+1s", "1m1s"},
 		{"1970-01-01T00:00:00+00:00 + 1m40s", "1970-01-01T00:01:40+00:00"},
@@ -58,3 +61,552 @@ func TestNow(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "1970-01-01T00:00:42+00:00", actual)
 }
+
+func TestTimeZones(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"1970-01-01T00:01:40Z", "100"},
+		{"1970-01-01T00:01:40.5Z + 1s", "1970-01-01T00:01:41.5+00:00"},
+		{"1969-12-31T19:00:00-05:00 - 1970-01-01T00:00:00Z", "0s"},
+		{"now in Europe/Warsaw", "1970-01-01T01:00:00+01:00"},
+		{"now + 1h in America/New_York", "1969-12-31T20:00:00-05:00"},
+		{"100 in UTC", "1970-01-01T00:01:40+00:00"},
+		{"1970-01-01T00:01:40Z in Europe/Warsaw", "1970-01-01T01:01:40+01:00"},
+		{"1970-01-01T00:01:40+01:00 in UTC", "1969-12-31T23:01:40+00:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSnowflakeFlag(t *testing.T) {
+	epoch, err := parseSnowflakeEpoch("discord")
+	assert.NoError(t, err)
+	defaultOptions.snowflakeEpoch = epoch
+	defer func() { defaultOptions.snowflakeEpoch = time.Time{} }()
+	actual, err := handleLine("175928847299117063")
+	assert.NoError(t, err)
+	assert.Equal(t, "2016-04-30T11:18:25.796+00:00", actual)
+	// The shorter numbers are still epoch timestamps.
+	actual, err = handleLine("1698603564")
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-10-29T18:19:24+00:00", actual)
+
+	_, err = parseSnowflakeEpoch("slack")
+	assert.EqualError(t, err, `unknown snowflake epoch "slack", expected twitter, discord, or epoch milliseconds`)
+}
+
+func TestTimeZoneFlag(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	loc, err := loadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	defaultOptions.loc = loc
+	defer func() { defaultOptions.loc = time.UTC }()
+	actual, err := handleLine("now")
+	assert.NoError(t, err)
+	assert.Equal(t, "1970-01-01T09:00:00+09:00", actual)
+}
+
+func TestUnknownTimeZone(t *testing.T) {
+	_, err := handleLine("now in Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestCalendarPeriods(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now - 3d", "1969-12-29T00:00:00+00:00"},
+		{"2023-01-31T00:00:00+00:00 + 1mo", "2023-03-03T00:00:00+00:00"},
+		{"2023-01-31T00:00:00+00:00 + 1y1w", "2024-02-07T00:00:00+00:00"},
+		{"1d + 1h", "1d1h0m0s"},
+		{"1mo - 1d", "1mo-1d"},
+		{"-1d + 1d", "0s"},
+		{"2023-10-28T12:00:00+02:00 + 1d in Europe/Warsaw", "2023-10-29T12:00:00+01:00"},
+		{"2023-10-28T12:00:00+02:00 + 24h in Europe/Warsaw", "2023-10-29T11:00:00+01:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSubSecond(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"1698603564000", "2023-10-29T18:19:24+00:00"},
+		{"1698603564000000000 - 1698603564", "0s"},
+		{"now + 250ms", "1970-01-01T00:00:00.25+00:00"},
+		{"1.5s + 500ms", "2s"},
+		{"10us - 3ns", "9.997µs"},
+		{"1698603564.123456789", "2023-10-29T18:19:24.123456789+00:00"},
+		{"2023-10-29T18:19:24.123456789Z", "1698603564.123456789"},
+		{"1698603564123456789 - 1698603564.123456788", "1ns"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestEpochUnitFlag(t *testing.T) {
+	defaultOptions.epochUnit = p.EpochMillis
+	defer func() { defaultOptions.epochUnit = p.EpochAuto }()
+	actual, err := handleLine("1500")
+	assert.NoError(t, err)
+	assert.Equal(t, "1970-01-01T00:00:01.5+00:00", actual)
+}
+
+func TestExpressions(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"(1970-01-01T00:01:40+00:00 - 1970-01-01T00:00:00+00:00) / 4", "25s"},
+		{"2 * 15m + now", "1970-01-01T00:30:00+00:00"},
+		{"now + 2 * 15m", "1970-01-01T00:30:00+00:00"},
+		{"15m * 2", "30m0s"},
+		{"(now - 1h) - (now - 3h)", "2h0m0s"},
+		{"90m / 1h", "1.5"},
+		{"90m/1h*2", "3"},
+		{"-(1h + 1m)", "-1h1m0s"},
+		{"now - -1h", "1970-01-01T01:00:00+00:00"},
+		{"-2 * 1h", "-2h0m0s"},
+		{"1h / 3", "20m0s"},
+		{"2 * 1mo", "2mo"},
+		{"2w / 2", "7d"},
+		{"((100))", "1970-01-01T00:01:40+00:00"},
+		{"( now - 1h ) + 30m", "1969-12-31T23:30:00+00:00"},
+		{"now", "1970-01-01T00:00:00+00:00"},
+		{"1h", "1h0m0s"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+		pos      int
+	}{
+		{"now * now", "cannot multiply two timestamps", 4},
+		{"now * 2", "cannot multiply a timestamp and a number", 4},
+		{"1970-01-01T00:00:00Z * 1970-01-01T00:00:00Z", "cannot multiply two timestamps", 21},
+		{"now + now", "cannot add two timestamps", 4},
+		{"1h - now", "cannot subtract a timestamp from a period", 3},
+		{"now / 2", "cannot divide a timestamp by a number", 4},
+		{"-now", "cannot negate a timestamp", 0},
+		{"1h / 0", "division by zero", 3},
+		{"1mo / 1d", "cannot divide calendar periods 1mo and 1d", 4},
+		{"1mo * 1.5", "cannot multiply calendar period 1mo by 1.5", 4},
+		{"(now - 1h", "missing closing parenthesis", 0},
+		{"now -", "expected operand after -", 4},
+		{"1h floor now", "cannot floor a period to a timestamp", 3},
+		{"now round 2d", "cannot round to calendar period 2d, only to 1d, 1w, 1mo or 1y", 4},
+		{"now ceil 0s", "cannot ceil to 0s, the period must be positive", 4},
+		{"1mo round 1h", "cannot round calendar periods 1mo and 1h0m0s", 4},
+		{"now < 1h", "cannot compare a timestamp and a period", 4},
+		{"1mo < 1d", "cannot compare calendar periods 1mo and 1d", 4},
+		{"now .. 1h", "cannot make an interval of a timestamp and a period", 4},
+		{"1h in (now .. now)", "cannot test if a period is in an interval", 3},
+		{"(now .. now) < (now .. now)", "cannot compare an interval and an interval", 13},
+		{"length(now)", "cannot take the length of a timestamp", 0},
+		{"2 * 3 round 4", "cannot round a number to a number", 6},
+		{"2*3 .. 4", "cannot make an interval of a number and a number", 4},
+		{"(2*3) union 4", "cannot union a number and a number", 6},
+		{"now .. now - 1h", "interval ends at 1969-12-31T23:00:00+00:00 before it starts at 1970-01-01T00:00:00+00:00", 4},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+			if cerr, ok := err.(p.CursorError); assert.True(t, ok) {
+				assert.Equal(t, tc.pos, cerr.Cursor().Pos)
+			}
+		})
+	}
+}
+
+func TestOutputFormatSuffix(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now + 1h as epoch", "3600"},
+		{"now + 1h as epoch-ms", "3600000"},
+		{"1970-01-01T00:01:40Z as rfc1123", "Thu, 01 Jan 1970 00:01:40 UTC"},
+		{"100 as date", "1970-01-01"},
+		{`now as "%Y-%m-%d %H:%M"`, "1970-01-01 00:00"},
+		{`now as '%H:%M' in Asia/Tokyo`, "09:00"},
+		{`now in Asia/Tokyo as %H:%M`, "09:00"},
+		{"now - 1h as epoch in Europe/Warsaw", "-3600"},
+		{"1h as epoch", "1h0m0s"},
+		{"now - 150m as relative", "2h30m ago"},
+		{"every 1d from now - 1d to now + 1d as relative", "1d ago\nnow\nin 1d"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestOutputFormatFlag(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	f, err := parseTimeFormat("syslog")
+	assert.NoError(t, err)
+	defaultOptions.timeFormat = f
+	defer func() { defaultOptions.timeFormat = nil }()
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now", "Jan  1 00:00:00"},
+		{"1970-01-01T00:01:40Z", "Jan  1 00:01:40"},
+		{"100", "Jan  1 00:01:40"},
+		{"now as iso", "1970-01-01T00:00:00+00:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestUnknownOutputFormat(t *testing.T) {
+	_, err := handleLine("now as fortnight")
+	assert.Error(t, err)
+}
+
+func TestPeriodOutput(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"2023-10-29T19:40:39+00:00 - 2022-09-28T18:22:32+00:00 as compact", "396d 1h 18m 7s"},
+		{"2023-10-29T19:40:39+00:00 - 2022-09-28T18:22:32+00:00 in h", "9505.30h"},
+		{"2023-10-29T19:40:39+00:00 - 2022-09-28T18:22:32+00:00 as iso", "P396DT1H18M7S"},
+		{"2023-10-29T19:40:39+00:00 - 2022-09-28T18:22:32+00:00 as verbose", "1 year, 1 month, 1 day, 1 hour, 18 minutes, 7 seconds"},
+		{"250ms in µs", "250000.00µs"},
+		{"now + PT1H30M", "1970-01-01T01:30:00+00:00"},
+		{"P1D - PT1H", "1d-1h0m0s"},
+		{"PT1H30M in m", "90.00m"},
+		{"now + 1h as iso", "1970-01-01T01:00:00+00:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestInputFormats(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 11, 15, 12, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"[29/Oct/2023:19:40:09 +0100] - 29/Oct/2023:18:40:09 +0000", "0s"},
+		{"Sun, 29 Oct 2023 19:40:09 GMT + 1h", "2023-10-29T20:40:09+00:00"},
+		{"Oct 29 19:40:09 - 2023-10-29 19:40:08", "1s"},
+		{"2023-10-29 19:40:09.123 as epoch", "1698608409.123"},
+		{"2023-10-29 19:40:09 in Europe/Warsaw", "2023-10-29T19:40:09+01:00"},
+		{"2023-10-29 19:40:09 in Europe/Warsaw as epoch", "1698604809"},
+		{"datetime.datetime(2023, 10, 29, 19, 40, 9) - 1d", "2023-10-28T19:40:09+00:00"},
+		{"Oct 29 19:40:09", "1698608409"},
+		{"01HDXBVRQF0000000000000000", "2023-10-29T09:22:26.671+00:00"},
+		{"018b7c2e-7b40-7000-8000-000000000000 as epoch-ms", "1698595502912"},
+		{"653eaa0d1c9d440000a1b2c3 - 01HDXBVRQF0000000000000000", "9h30m34.329s"},
+		{"2XSZ4o2qFdS9LnFA0u1Hk0IVXz3 in Europe/Warsaw", "2023-10-30T01:04:12+01:00"},
+		{"2023-10-29", "1698537600"},
+		{"2023-10-29 + 1h", "2023-10-29T01:00:00+00:00"},
+		{"2023-10-29 19:40 as iso", "2023-10-29T19:40:00+00:00"},
+		{"2023-10-29 10:00-10m as iso", "2023-10-29T09:50:00+00:00"},
+		{"2023-10-29 10:00:00+15m as iso", "2023-10-29T10:15:00+00:00"},
+		{"2023-10-29 10:00:00-1000ms as iso", "2023-10-29T09:59:59+00:00"},
+		{"2023-10-29 10:00+01 - 1h", "2023-10-29T08:00:00+00:00"},
+		{"19:40 - 09:00", "10h40m0s"},
+		{"09:30 in Europe/Warsaw as iso", "2023-11-15T09:30:00+01:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestAnchors(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 11, 15, 13, 14, 15, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"today", "2023-11-15T00:00:00+00:00"},
+		{"yesterday + 12h", "2023-11-14T12:00:00+00:00"},
+		{"now - start of day", "13h14m15s"},
+		{"start of week in Asia/Tokyo", "2023-11-13T00:00:00+09:00"},
+		{"end of month - start of month as compact", "29d 23h 59m 59.999999999s"},
+		{"next monday 09:00 - now", "115h45m45s"},
+		{"last friday", "2023-11-10T00:00:00+00:00"},
+		{"3 hours ago", "2023-11-15T10:14:15+00:00"},
+		{"in 2 days - now", "48h0m0s"},
+		{"2h ago < now", "true"},
+		{"in 1 day in Asia/Tokyo", "2023-11-16T22:14:15+09:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRounding(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 11, 15, 13, 14, 15, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now floor 15m", "2023-11-15T13:00:00+00:00"},
+		{"now ceil 15m", "2023-11-15T13:15:00+00:00"},
+		{"now round 1h", "2023-11-15T13:00:00+00:00"},
+		{"1698608409 round 1h", "2023-10-29T20:00:00+00:00"},
+		{"2023-10-29T19:40:09Z round 1h", "2023-10-29T20:00:00+00:00"},
+		{"2023-10-29T19:30:00Z round 1h", "2023-10-29T20:00:00+00:00"},
+		{"2023-10-29T19:00:00Z ceil 1h", "2023-10-29T19:00:00+00:00"},
+		{"now ceil to day", "2023-11-16T00:00:00+00:00"},
+		{"now floor week", "2023-11-13T00:00:00+00:00"},
+		{"now floor 1mo", "2023-11-01T00:00:00+00:00"},
+		{"now round year", "2024-01-01T00:00:00+00:00"},
+		{"now floor 1h in Asia/Kolkata", "2023-11-15T18:00:00+05:30"},
+		{"now floor day in Asia/Tokyo", "2023-11-15T00:00:00+09:00"},
+		{"now - 1h floor 15m", "2023-11-15T12:00:00+00:00"},
+		{"1h23m41s round 1m", "1h24m0s"},
+		{"1h23m41s floor 1m", "1h23m0s"},
+		{"-1h30m floor 1h", "-2h0m0s"},
+		{"-1h30m ceil 1h", "-1h0m0s"},
+		{"now - start of day round 1h", "13h0m0s"},
+		{"2023-10-29T19:40:09Z floor 7m", "2023-10-29T19:36:00+00:00"},
+		{"2023-10-29T19:40:09Z ceil 7m", "2023-10-29T19:43:00+00:00"},
+		{"2023-10-29T23:58:00Z ceil 7m", "2023-10-30T00:00:00+00:00"},
+		{"2023-10-29T19:40:09Z floor 7m in Asia/Kolkata", "2023-10-30T01:10:00+05:30"},
+		{"2023-03-26T03:10:00+02:00 floor 4h in Europe/Warsaw", "2023-03-26T00:00:00+01:00"},
+		{"2023-03-26T01:50:00+01:00 ceil 1h in Europe/Warsaw", "2023-03-26T03:00:00+02:00"},
+		{"2023-10-29T02:30:00+02:00 floor 1h in Europe/Warsaw", "2023-10-29T02:00:00+02:00"},
+		{"2023-10-29T02:30:00+01:00 floor 1h in Europe/Warsaw", "2023-10-29T02:00:00+01:00"},
+		{"2023-10-29T01:50:00+02:00 ceil 1h in Europe/Warsaw", "2023-10-29T02:00:00+02:00"},
+		{"2023-10-29T04:10:00+01:00 floor 6h in Europe/Warsaw", "2023-10-29T00:00:00+02:00"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 20, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now - 1h < now", "true"},
+		{"now <= now", "true"},
+		{"now > now", "false"},
+		{"2023-10-29T20:00:00Z == 1698609600", "true"},
+		{"2023-10-29T21:00:00+01:00 != now", "false"},
+		{"1h >= 30m", "true"},
+		{"90m / 1h > 1", "true"},
+		{"now - start of day == 20h", "true"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 20, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"2023-10-29T19:40:09+00:00 in (now-2h .. now)", "true"},
+		{"2023-10-29T17:40:09+00:00 in (now-2h .. now)", "false"},
+		{"now in (now-2h .. now)", "true"},
+		{"now - 1h .. now", "2023-10-29T19:00:00+00:00 .. 2023-10-29T20:00:00+00:00"},
+		{"now - 1h .. now in Europe/Warsaw", "2023-10-29T20:00:00+01:00 .. 2023-10-29T21:00:00+01:00"},
+		{"now - 1h .. now as epoch", "1698606000 .. 1698609600"},
+		{"(now-1h .. now) in (today .. tomorrow)", "true"},
+		{"(now-1h .. now+5h) in (today .. tomorrow)", "false"},
+		{"(now-2h .. now) overlaps (now-1h .. now+1h)", "true"},
+		{"(now-2h .. now-1h) overlaps (now .. now+1h)", "false"},
+		{"(now-2h .. now) intersect (now-1h .. now+1h)", "2023-10-29T19:00:00+00:00 .. 2023-10-29T20:00:00+00:00"},
+		{"(now-2h .. now) union (now-1h .. now+1h)", "2023-10-29T18:00:00+00:00 .. 2023-10-29T21:00:00+00:00"},
+		{"(now .. now+1h) == (now .. now+1h)", "true"},
+		{"length(now-2h .. now)", "2h0m0s"},
+		{"length((now-2h .. now) intersect (now-1h .. now+1h)) as compact", "1h"},
+		{"length(today .. now) > 12h", "true"},
+	} {
+		t.Run(fmt.Sprintf("%s == %s", tc.input, tc.expected), func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestIntervalsDoNotOverlap(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, input := range []string{
+		"(now-2h .. now-1h) union (now .. now+1h)",
+		"(now-2h .. now-1h) intersect (now .. now+1h)",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := handleLine(input)
+			assert.ErrorContains(t, err, "do not overlap")
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 20, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected []string
+	}{
+		{"every 15m from 2023-10-29T00:00:00+00:00 to +1h", []string{
+			"2023-10-29T00:00:00+00:00",
+			"2023-10-29T00:15:00+00:00",
+			"2023-10-29T00:30:00+00:00",
+			"2023-10-29T00:45:00+00:00",
+			"2023-10-29T01:00:00+00:00",
+		}},
+		{"every 25m from now to now + 1h as epoch", []string{"1698609600", "1698611100", "1698612600"}},
+		{"every 1mo from 2023-01-31T00:00:00Z to 2023-06-01T00:00:00Z", []string{
+			"2023-01-31T00:00:00+00:00",
+			"2023-02-28T00:00:00+00:00",
+			"2023-03-31T00:00:00+00:00",
+			"2023-04-30T00:00:00+00:00",
+			"2023-05-31T00:00:00+00:00",
+		}},
+		{"every 1mo from 2023-01-31T00:00:00Z to 2023-04-01T00:00:00Z", []string{
+			"2023-01-31T00:00:00+00:00",
+			"2023-02-28T00:00:00+00:00",
+			"2023-03-31T00:00:00+00:00",
+		}},
+		{"every 1y from 2024-02-29T12:00:00Z to +2y", []string{
+			"2024-02-29T12:00:00+00:00",
+			"2025-02-28T12:00:00+00:00",
+			"2026-02-28T12:00:00+00:00",
+		}},
+		{"every 1d from 2023-10-28T00:00:00+02:00 to +2d in Europe/Warsaw", []string{
+			"2023-10-28T00:00:00+02:00",
+			"2023-10-29T00:00:00+02:00",
+			"2023-10-30T00:00:00+01:00",
+		}},
+		{"every 1h from today to today", []string{"2023-10-29T00:00:00+00:00"}},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, strings.Join(tc.expected, "\n"), actual)
+		})
+	}
+}
+
+func TestEveryPairs(t *testing.T) {
+	defaultOptions.pairs = true
+	defer func() { defaultOptions.pairs = false }()
+	actual, err := handleLine("every 25m from 2023-10-29T00:00:00Z to +1h")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"2023-10-29T00:00:00+00:00\t2023-10-29T00:25:00+00:00",
+		"2023-10-29T00:25:00+00:00\t2023-10-29T00:50:00+00:00",
+		"2023-10-29T00:50:00+00:00\t2023-10-29T01:00:00+00:00",
+	}, "\n"), actual)
+}
+
+func TestEveryErrors(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"every 0s from now to +1h", "the step of every must be positive, not 0s"},
+		{"every -1h from now to +1h", "the step of every must be positive, not -1h0m0s"},
+		{"every now from now to +1h", "the step of every must be a period, not a timestamp"},
+		{"every 1h from 1h to +1h", "the start of every must be a timestamp, not a period"},
+		{"every 1s from 1970-01-01T00:00:00Z to +30d", "the sequence is longer than 100000 timestamps"},
+		{"every 1h from now", "expected to"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
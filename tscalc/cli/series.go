@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"strings"
+	"time"
+)
+
+// maxSeriesLen limits the length of the sequences, so a typo like `every 1s from 2000-01-01...` does not print
+// forever.
+const maxSeriesLen = 100_000
+
+// everyNode is the sequence of timestamps, e.g. `every 15m from today to +6h`.
+type everyNode struct {
+	step p.Node
+	from p.Node
+	to   p.Node
+	cur  p.Cursor
+}
+
+func (n everyNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n everyNode) String() string {
+	return fmt.Sprintf("(every %s from %s to %s)", n.step, n.from, n.to)
+}
+
+func buildEvery(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	if seq.Len() != 6 {
+		expected := []string{"period after every", "from", "start after from", "to", "end after to"}[seq.Len()-1]
+		return nil, cursorError{err: fmt.Errorf("expected %s", expected), cur: seq.Cursor()}
+	}
+	return everyNode{step: seq.Nodes[1], from: seq.Nodes[3], to: seq.Nodes[5], cur: seq.Cursor()}, nil
+}
+
+// seriesNode is the evaluated sequence. The times are the steps from the start up to and including the end.
+type seriesNode struct {
+	times []time.Time
+	end   time.Time
+	cur   p.Cursor
+}
+
+func (n seriesNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n seriesNode) String() string {
+	lines := make([]string, len(n.times))
+	for i, t := range n.times {
+		lines[i] = fmt.Sprint(p.IsoTimeNode{Time: t})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// evalEvery computes the sequence. The end can be a timestamp or a period after the start, e.g. `to +6h`.
+// The i-th timestamp is the start plus i steps, so the calendar periods do not drift, e.g. `every 1mo` from
+// the 31st comes back to the 31st in the months that have it, and is the last day of the shorter months.
+func (e evaluator) evalEvery(n everyNode) (p.Node, error) {
+	values := make([]p.Node, 3)
+	for i, node := range []p.Node{n.step, n.from, n.to} {
+		value, err := e.eval(node)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	step, ok := values[0].(p.PeriodNode)
+	if !ok {
+		return nil, cursorError{err: fmt.Errorf("the step of every must be a period, not %s", withArticle(typeName(values[0]))), cur: n.step.Cursor()}
+	}
+	from, ok := values[1].(p.IsoTimeNode)
+	if !ok {
+		return nil, cursorError{err: fmt.Errorf("the start of every must be a timestamp, not %s", withArticle(typeName(values[1]))), cur: n.from.Cursor()}
+	}
+	var end time.Time
+	switch to := values[2].(type) {
+	case p.IsoTimeNode:
+		end = to.Time
+	case p.PeriodNode:
+		end = to.AddTo(from.Time, e.loc)
+	default:
+		return nil, cursorError{err: fmt.Errorf("the end of every must be a timestamp or a period, not %s", withArticle(typeName(to))), cur: n.to.Cursor()}
+	}
+	if !step.AddTo(from.Time, e.loc).After(from.Time) {
+		return nil, cursorError{err: fmt.Errorf("the step of every must be positive, not %s", step), cur: n.step.Cursor()}
+	}
+
+	series := seriesNode{end: end, cur: n.cur}
+	for i := 0; ; i++ {
+		offset, err := multiplyPeriod(step, float64(i))
+		if err != nil {
+			return nil, cursorError{err: err, cur: n.step.Cursor()}
+		}
+		t := addClamped(offset, from.Time, e.loc)
+		if t.After(end) {
+			break
+		}
+		if len(series.times) == maxSeriesLen {
+			return nil, cursorError{err: fmt.Errorf("the sequence is longer than %d timestamps", maxSeriesLen), cur: n.cur}
+		}
+		series.times = append(series.times, t)
+	}
+	return series, nil
+}
+
+// formatSeries prints the timestamps of the sequence, one per line. With the pairs option every line is the
+// timestamp and the next one, separated by a tab. The last pair ends at the end of the sequence.
+func formatSeries(n seriesNode, opts options) string {
+	format := func(t time.Time) string {
+		s, _ := formatResult(p.IsoTimeNode{Time: t}, opts)
+		return s
+	}
+	lines := []string{}
+	for i, t := range n.times {
+		if !opts.pairs {
+			lines = append(lines, format(t))
+			continue
+		}
+		if !t.Before(n.end) {
+			break
+		}
+		next := n.end
+		if i+1 < len(n.times) {
+			next = n.times[i+1]
+		}
+		lines = append(lines, format(t)+"\t"+format(next))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addClamped adds the period to the time like AddTo, but the months that do not have the day of the time end on
+// their last day, e.g. one month after January 31st is February 28th rather than March 3rd.
+func addClamped(period p.PeriodNode, t time.Time, loc *time.Location) time.Time {
+	if period.Months == 0 {
+		return period.AddTo(t, loc)
+	}
+	local := t.In(loc)
+	year, month, day := local.Date()
+	if last := time.Date(year, month+time.Month(period.Months)+1, 0, 0, 0, 0, 0, loc).Day(); day > last {
+		day = last
+	}
+	hour, minute, sec := local.Clock()
+	t = time.Date(year, month+time.Month(period.Months), day, hour, minute, sec, local.Nanosecond(), loc)
+	return t.AddDate(0, 0, period.Days).Add(period.Duration)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// strLast is the name of the variable with the result of the last line.
+const strLast = "_"
+
+// reserved are the words that cannot be the names of the variables, because they mean something else in
+// the expressions.
+var reserved = map[string]bool{
+	strNow: true, strLast: true, strIn: true, strOverlaps: true, strUnion: true, strIntersect: true,
+	strFloor: true, strCeil: true, strRound: true, "to": true, "from": true, "every": true, "length": true,
+	"as": true, "today": true, "yesterday": true, "tomorrow": true, "start": true, "end": true, "last": true,
+	"next": true, strWorkdays: true, strWorkhours: true, "cron": true, "prev": true, "after": true, "before": true,
+	"exp": true, "iat": true, "nbf": true, "notbefore": true, "notafter": true, "ago": true,
+}
+
+// session evaluates the lines one after another and keeps the variables between them.
+type session struct {
+	vars map[string]p.Node
+	// zones are the time zones in which the timestamps are printed as a table, set with -zones.
+	zones []*time.Location
+	// describe prints the calendar facts about the timestamps instead of the timestamps, set with -describe.
+	describe bool
+}
+
+func newSession() *session {
+	return &session{vars: map[string]p.Node{}}
+}
+
+// names returns the names of the defined variables.
+func (s *session) names() []string {
+	names := []string{}
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printLine prints the result of the line to the writer, or the error with the caret pointing where it happened.
+// The empty lines and the comments starting with # are skipped.
+func (s *session) printLine(w io.Writer, line string) (p.Node, error) {
+	if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil, nil
+	}
+	result, opts, err := s.evalLine(line)
+	var res string
+	if err == nil {
+		res, err = s.format(result, opts)
+	}
+	if err != nil {
+		printError(w, err)
+		return nil, err
+	}
+	fmt.Fprintln(w, res)
+	return result, nil
+}
+
+// run prints the results of the lines of the input and stops at the first line that fails. The result is false if
+// a line failed or any of the conditions is false, so it can be the exit status like in `test`.
+func (s *session) run(in io.Reader, out io.Writer) (bool, error) {
+	ok := true
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		result, err := s.printLine(out, scanner.Text())
+		if err != nil {
+			return false, nil
+		}
+		if b, isBool := result.(boolNode); isBool && !b.value {
+			ok = false
+		}
+	}
+	return ok, scanner.Err()
+}
+
+// format prints the result. The timestamps are described or printed in all the zones if it is set.
+func (s *session) format(result p.Node, opts options) (string, error) {
+	var t p.IsoTimeNode
+	switch n := result.(type) {
+	case p.IsoTimeNode:
+		t = n
+	case p.EpochTimeNode:
+		t = n.ToIsoTimeNode()
+	default:
+		return formatResult(result, opts)
+	}
+	switch {
+	case s.describe:
+		return describe(t, opts)
+	case len(s.zones) > 0:
+		return formatZones(t, s.zones, opts)
+	}
+	return formatResult(result, opts)
+}
+
+// printError prints the error, and the input with the caret pointing at the position of the error.
+func printError(w io.Writer, err error) {
+	fmt.Fprintln(w, err)
+	if nerr, ok := err.(p.CursorError); ok {
+		fmt.Fprintln(w, nerr.Cursor().Input)
+		fmt.Fprintf(w, "%s^\n", strings.Repeat("_", nerr.Cursor().Pos))
+	}
+}
+
+// runInteractive reads the lines from the terminal with line editing and history, until Ctrl-D or Ctrl-C.
+// The errors do not end the session.
+func (s *session) runInteractive() error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "> ")
+	if width, height, err := term.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.printLine(t, line)
+	}
+}
+
+// variableNode is the reference to the variable, e.g. `deploy` in `deploy + 1h`.
+type variableNode struct {
+	name string
+	cur  p.Cursor
+}
+
+func (n variableNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n variableNode) String() string {
+	return n.name
+}
+
+// variable parses the names of the defined variables.
+func variable(names []string) p.Parser {
+	if len(names) == 0 {
+		return p.FirstOf()
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return p.Map(
+		p.Regex(`(?:`+strings.Join(quoted, "|")+`)\b`),
+		func(node p.Node) (p.Node, error) {
+			name := node.(p.LiteralNode)
+			return variableNode{name: name.Literal, cur: name.Cursor()}, nil
+		},
+	)
+}
+
+// assignNode sets the variable to the value of the expression, e.g. `deploy = 2023-10-29T19:40:09Z`.
+type assignNode struct {
+	name  string
+	value p.Node
+	cur   p.Cursor
+}
+
+func (n assignNode) Cursor() p.Cursor {
+	return n.cur
+}
+
+func (n assignNode) String() string {
+	return fmt.Sprintf("%s = %s", n.name, n.value)
+}
+
+func buildAssign(node p.Node) (p.Node, error) {
+	seq := node.(p.SequenceNode)
+	name := seq.Nodes[0].(p.LiteralNode)
+	if reserved[name.Literal] {
+		return nil, cursorError{err: fmt.Errorf("cannot assign to %s, it is a reserved word", name.Literal), cur: name.Cursor()}
+	}
+	if seq.Len() != 2 {
+		return nil, cursorError{err: fmt.Errorf("expected value after ="), cur: name.Cursor()}
+	}
+	return assignNode{name: name.Literal, value: seq.Nodes[1], cur: name.Cursor()}, nil
+}
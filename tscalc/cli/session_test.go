@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionVariables(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 20, 0, 0, 0, time.UTC)
+	}
+	s := newSession()
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"deploy = 2023-10-29T19:40:09+00:00", "2023-10-29T19:40:09+00:00"},
+		{"deploy + 1h", "2023-10-29T20:40:09+00:00"},
+		{"_ - deploy", "1h0m0s"},
+		{"window = deploy - 15m .. deploy + 15m", "2023-10-29T19:25:09+00:00 .. 2023-10-29T19:55:09+00:00"},
+		{"now in window", "false"},
+		{"step=_", "false"},
+		{"deploy in Europe/Warsaw", "2023-10-29T20:40:09+01:00"},
+		{"1698603564", "2023-10-29T18:19:24+00:00"},
+		{"_ + 1s", "2023-10-29T18:19:25+00:00"},
+		{"deploy == deploy", "true"},
+		{"d = 90m", "1h30m0s"},
+		{"d / 1h", "1.5"},
+		{"every d from deploy to +3h", "2023-10-29T19:40:09+00:00\n2023-10-29T21:10:09+00:00\n2023-10-29T22:40:09+00:00"},
+		{"nowish = 1h", "1h0m0s"},
+		{"nowish + 1h", "2h0m0s"},
+		{"now - nowish", "2023-10-29T19:00:00+00:00"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := s.handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	s := newSession()
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now = 1h", "cannot assign to now, it is a reserved word"},
+		{"_ = 1h", "cannot assign to _, it is a reserved word"},
+		{"x =", "expected value after ="},
+		{"x + 1h", "failed to parse whole input"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := s.handleLine(tc.input)
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestPrintLine(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Unix(0, 0)
+	}
+	s := newSession()
+	out := bytes.Buffer{}
+	for _, line := range []string{"# a comment", "x = now", "", "x - 1h", "x * 2"} {
+		s.printLine(&out, line)
+	}
+	assert.Equal(t, `1970-01-01T00:00:00+00:00
+1969-12-31T23:00:00+00:00
+cannot multiply a timestamp and a number
+x * 2
+__^
+`, out.String())
+}
+
+func TestRunExitStatus(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 20, 0, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		input    string
+		expected bool
+		out      string
+	}{
+		{"now - 1h\n1h < 2h\n", true, "2023-10-29T19:00:00+00:00\ntrue\n"},
+		{"1h > 2h\n1h\n", false, "false\n1h0m0s\n"},
+		{"2023-02-30T19:40:09Z in (now-2h .. now)\n1h\n", false, `error while parsing 2023-02-30T19:40:09Z: parsing time "2023-02-30T19:40:09Z": day out of range
+2023-02-30T19:40:09Z in (now-2h .. now)
+^
+`},
+		{"1h + 1.5d ago\n", false, `calendar period 1.5d must be an integer: strconv.Atoi: parsing "1.5": invalid syntax
+1h + 1.5d ago
+_____^
+`},
+		{"now * 2\n", false, "cannot multiply a timestamp and a number\nnow * 2\n____^\n"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			out := bytes.Buffer{}
+			ok, err := newSession().run(strings.NewReader(tc.input), &out)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, ok)
+			assert.Equal(t, tc.out, out.String())
+		})
+	}
+}
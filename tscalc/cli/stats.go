@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	p "lib/tscalc/parse"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// histogramWidth is the length of the longest bar of the histogram.
+const histogramWidth = 40
+
+// percentiles are printed in the statistics.
+var percentiles = []float64{50, 90, 99}
+
+// stats are the statistics of the periods.
+type stats struct {
+	count  int
+	min    time.Duration
+	max    time.Duration
+	mean   time.Duration
+	stddev time.Duration
+	// percentiles are in the same order as the global percentiles.
+	percentiles []time.Duration
+}
+
+// computeStats returns the statistics of the sorted periods. The standard deviation is of the population.
+func computeStats(sorted []time.Duration) stats {
+	s := stats{count: len(sorted)}
+	if len(sorted) == 0 {
+		return s
+	}
+	s.min, s.max = sorted[0], sorted[len(sorted)-1]
+	sum := 0.0
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	variance := 0.0
+	for _, d := range sorted {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	variance /= float64(len(sorted))
+	s.mean = time.Duration(math.Round(mean))
+	s.stddev = time.Duration(math.Round(math.Sqrt(variance)))
+	for _, q := range percentiles {
+		s.percentiles = append(s.percentiles, percentile(sorted, q))
+	}
+	return s
+}
+
+// percentile returns the q-th percentile of the sorted periods, interpolated linearly between the closest ranks.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := q / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + time.Duration(math.Round(frac*float64(sorted[lower+1]-sorted[lower])))
+}
+
+// bucketSpec is either the number of the buckets of the histogram, or their width.
+type bucketSpec struct {
+	count int
+	width time.Duration
+}
+
+// parseBuckets parses the number of the buckets, e.g. "10", or their width, e.g. "100ms".
+func parseBuckets(spec string) (bucketSpec, error) {
+	if count, err := strconv.Atoi(spec); err == nil {
+		if count < 1 {
+			return bucketSpec{}, fmt.Errorf("the number of buckets must be positive, not %d", count)
+		}
+		if count > maxSeriesLen {
+			return bucketSpec{}, fmt.Errorf("the number of buckets must be at most %d, not %d", maxSeriesLen, count)
+		}
+		return bucketSpec{count: count}, nil
+	}
+	node, rest, err := p.Period.Parse(p.NewCursor(spec))
+	if err != nil || node == nil || !rest.Ended() {
+		return bucketSpec{}, fmt.Errorf("invalid buckets %q, expected a number like 10 or a period like 100ms", spec)
+	}
+	period := node.(p.PeriodNode)
+	if period.IsCalendar() || period.Duration <= 0 {
+		return bucketSpec{}, fmt.Errorf("the width of buckets must be a positive fixed period, not %s", period)
+	}
+	return bucketSpec{width: period.Duration}, nil
+}
+
+// bucket is the range [from, to) of the histogram. The last bucket includes its end.
+type bucket struct {
+	from  time.Duration
+	to    time.Duration
+	count int
+}
+
+// histogram counts the sorted periods in the buckets. The buckets of the given width are aligned to its multiples.
+func histogram(sorted []time.Duration, spec bucketSpec) ([]bucket, error) {
+	if len(sorted) == 0 {
+		return nil, nil
+	}
+	min, max := sorted[0], sorted[len(sorted)-1]
+	start, width, count := min, spec.width, spec.count
+	if width > 0 {
+		start = min - ((min%width)+width)%width
+		if (max-start)/width >= maxSeriesLen {
+			return nil, fmt.Errorf("the histogram has more than %d buckets of %s, use wider buckets", maxSeriesLen, width)
+		}
+		count = int((max-start)/width) + 1
+	} else {
+		if min == max {
+			return []bucket{{from: min, to: max, count: len(sorted)}}, nil
+		}
+		width = (max - min + time.Duration(count) - 1) / time.Duration(count)
+	}
+	buckets := make([]bucket, count)
+	for i := range buckets {
+		buckets[i].from = start + time.Duration(i)*width
+		buckets[i].to = buckets[i].from + width
+	}
+	for _, d := range sorted {
+		i := int((d - start) / width)
+		if i >= count {
+			i = count - 1
+		}
+		buckets[i].count++
+	}
+	return buckets, nil
+}
+
+// formatStats prints the statistics and the histogram, with the periods in the output format.
+func formatStats(s stats, buckets []bucket, opts options) (string, error) {
+	format := func(d time.Duration) (string, error) {
+		return formatResult(p.PeriodNode{Duration: d}, opts)
+	}
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "count   %d\n", s.count)
+	if s.count == 0 {
+		return b.String(), nil
+	}
+	rows := []struct {
+		name  string
+		value time.Duration
+	}{{"min", s.min}, {"max", s.max}, {"mean", s.mean}, {"stddev", s.stddev}}
+	for i, q := range percentiles {
+		rows = append(rows, struct {
+			name  string
+			value time.Duration
+		}{fmt.Sprintf("p%g", q), s.percentiles[i]})
+	}
+	for _, row := range rows {
+		value, err := format(row.value)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%-7s %s\n", row.name, value)
+	}
+
+	b.WriteString("\n")
+	labels := make([][2]string, len(buckets))
+	fromWidth, toWidth, maxCount := 0, 0, 0
+	for i, bk := range buckets {
+		from, err := format(bk.from)
+		if err != nil {
+			return "", err
+		}
+		to, err := format(bk.to)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = [2]string{from, to}
+		fromWidth = maxInt(fromWidth, len(from))
+		toWidth = maxInt(toWidth, len(to))
+		maxCount = maxInt(maxCount, bk.count)
+	}
+	for i, bk := range buckets {
+		bar := strings.Repeat("#", (bk.count*histogramWidth+maxCount-1)/maxCount)
+		fmt.Fprintf(&b, "%*s .. %-*s | %-*s %d\n", fromWidth, labels[i][0], toWidth, labels[i][1], histogramWidth, bar, bk.count)
+	}
+	return b.String(), nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// runStats reads the periods, one per line, and prints their statistics. The lines are evaluated like in the normal
+// mode, so they can be the differences of timestamps. The lines which are not fixed periods are reported to errOut
+// and skipped. It returns the number of such lines.
+func runStats(in io.Reader, out, errOut io.Writer, spec bucketSpec) (int, error) {
+	s := newSession()
+	durations := []time.Duration{}
+	failed := 0
+	scanner := bufio.NewScanner(in)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		result, _, err := s.evalLine(line)
+		if err == nil {
+			if period, ok := result.(p.PeriodNode); !ok {
+				err = fmt.Errorf("expected a period, not %s", withArticle(typeName(result)))
+			} else if period.IsCalendar() {
+				err = fmt.Errorf("calendar period %s does not have fixed length", period)
+			} else {
+				durations = append(durations, period.Duration)
+				continue
+			}
+		}
+		fmt.Fprintf(errOut, "line %d: %v\n", lineNum, err)
+		failed++
+	}
+	if err := scanner.Err(); err != nil {
+		return failed, err
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	buckets, err := histogram(durations, spec)
+	if err != nil {
+		return failed, err
+	}
+	res, err := formatStats(computeStats(durations), buckets, defaultOptions)
+	if err != nil {
+		return failed, err
+	}
+	fmt.Fprint(out, res)
+	return failed, nil
+}
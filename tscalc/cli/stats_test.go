@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStats(t *testing.T) {
+	sorted := []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}
+	s := computeStats(sorted)
+	assert.Equal(t, 4, s.count)
+	assert.Equal(t, 1*time.Second, s.min)
+	assert.Equal(t, 4*time.Second, s.max)
+	assert.Equal(t, 2500*time.Millisecond, s.mean)
+	assert.Equal(t, 1118033989*time.Nanosecond, s.stddev)
+	assert.Equal(t, []time.Duration{2500 * time.Millisecond, 3700 * time.Millisecond, 3970 * time.Millisecond}, s.percentiles)
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, time.Second, percentile([]time.Duration{time.Second}, 99))
+	assert.Equal(t, 15*time.Second, percentile([]time.Duration{10 * time.Second, 20 * time.Second}, 50))
+	assert.Equal(t, 20*time.Second, percentile([]time.Duration{10 * time.Second, 20 * time.Second}, 100))
+}
+
+func TestHistogram(t *testing.T) {
+	sorted := []time.Duration{120 * time.Millisecond, 250 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, 1500 * time.Millisecond}
+	buckets, err := histogram(sorted, bucketSpec{width: 500 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, []bucket{
+		{0, 500 * time.Millisecond, 3},
+		{500 * time.Millisecond, time.Second, 1},
+		{time.Second, 1500 * time.Millisecond, 0},
+		{1500 * time.Millisecond, 2 * time.Second, 1},
+	}, buckets)
+	buckets, err = histogram(sorted, bucketSpec{count: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []bucket{
+		{120 * time.Millisecond, 810 * time.Millisecond, 3},
+		{810 * time.Millisecond, 1500 * time.Millisecond, 2},
+	}, buckets)
+	buckets, err = histogram([]time.Duration{time.Second, time.Second}, bucketSpec{count: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []bucket{{time.Second, time.Second, 2}}, buckets)
+}
+
+func TestHistogramTooManyBuckets(t *testing.T) {
+	_, err := histogram([]time.Duration{0, 1000 * time.Hour}, bucketSpec{width: time.Millisecond})
+	assert.EqualError(t, err, "the histogram has more than 100000 buckets of 1ms, use wider buckets")
+	_, err = histogram([]time.Duration{0, time.Hour}, bucketSpec{width: time.Nanosecond})
+	assert.Error(t, err)
+	buckets, err := histogram([]time.Duration{0, 99_999 * time.Millisecond}, bucketSpec{width: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Len(t, buckets, maxSeriesLen)
+}
+
+func TestParseBuckets(t *testing.T) {
+	spec, err := parseBuckets("10")
+	assert.NoError(t, err)
+	assert.Equal(t, bucketSpec{count: 10}, spec)
+	spec, err = parseBuckets("100ms")
+	assert.NoError(t, err)
+	assert.Equal(t, bucketSpec{width: 100 * time.Millisecond}, spec)
+	for _, input := range []string{"0", "1d", "0s", "ten", "1000000"} {
+		_, err := parseBuckets(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	input := strings.Join([]string{
+		"120ms",
+		"2023-10-29T19:40:10Z - 2023-10-29T19:40:09Z",
+		"",
+		"now",
+		"300ms",
+		"1mo",
+	}, "\n")
+	out, errOut := bytes.Buffer{}, bytes.Buffer{}
+	failed, err := runStats(strings.NewReader(input), &out, &errOut, bucketSpec{count: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, "line 4: expected a period, not a timestamp\nline 6: calendar period 1mo does not have fixed length\n", errOut.String())
+	assert.Equal(t, `count   3
+min     120ms
+max     1s
+mean    473.333333ms
+stddev  379.590423ms
+p50     300ms
+p90     860ms
+p99     986ms
+
+120ms .. 560ms | ######################################## 2
+560ms .. 1s    | ####################                     1
+`, out.String())
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// strftimeDirectives maps the strftime conversion characters to the functions rendering them.
+var strftimeDirectives = map[byte]func(time.Time) string{
+	'a': func(t time.Time) string { return t.Format("Mon") },
+	'A': func(t time.Time) string { return t.Format("Monday") },
+	'b': func(t time.Time) string { return t.Format("Jan") },
+	'B': func(t time.Time) string { return t.Format("January") },
+	'd': func(t time.Time) string { return t.Format("02") },
+	'e': func(t time.Time) string { return t.Format("_2") },
+	'F': func(t time.Time) string { return t.Format("2006-01-02") },
+	'H': func(t time.Time) string { return t.Format("15") },
+	'I': func(t time.Time) string { return t.Format("03") },
+	'j': func(t time.Time) string { return fmt.Sprintf("%03d", t.YearDay()) },
+	'm': func(t time.Time) string { return t.Format("01") },
+	'M': func(t time.Time) string { return t.Format("04") },
+	'p': func(t time.Time) string { return t.Format("PM") },
+	's': func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) },
+	'S': func(t time.Time) string { return t.Format("05") },
+	'f': func(t time.Time) string { return fmt.Sprintf("%06d", t.Nanosecond()/1000) },
+	'N': func(t time.Time) string { return fmt.Sprintf("%09d", t.Nanosecond()) },
+	'T': func(t time.Time) string { return t.Format("15:04:05") },
+	'u': func(t time.Time) string { return strconv.Itoa((int(t.Weekday())+6)%7 + 1) },
+	'w': func(t time.Time) string { return strconv.Itoa(int(t.Weekday())) },
+	'V': func(t time.Time) string { _, w := t.ISOWeek(); return fmt.Sprintf("%02d", w) },
+	'G': func(t time.Time) string { y, _ := t.ISOWeek(); return strconv.Itoa(y) },
+	'y': func(t time.Time) string { return t.Format("06") },
+	'Y': func(t time.Time) string { return t.Format("2006") },
+	'z': func(t time.Time) string { return t.Format("-0700") },
+	'Z': func(t time.Time) string { return t.Format("MST") },
+	'n': func(t time.Time) string { return "\n" },
+	't': func(t time.Time) string { return "\t" },
+	'%': func(t time.Time) string { return "%" },
+}
+
+// compileStrftime returns the format for strftime-style layout, e.g. "%Y-%m-%d %H:%M:%S". Besides the usual
+// directives, %f are microseconds and %N are nanoseconds.
+func compileStrftime(layout string) (timeFormat, error) {
+	parts := []func(time.Time) string{}
+	literal := strings.Builder{}
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			s := literal.String()
+			parts = append(parts, func(time.Time) string { return s })
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			literal.WriteByte(layout[i])
+			continue
+		}
+		if i+1 >= len(layout) {
+			return nil, fmt.Errorf("strftime layout %q ends with %%", layout)
+		}
+		i++
+		directive, ok := strftimeDirectives[layout[i]]
+		if !ok {
+			return nil, fmt.Errorf("unknown strftime directive %%%c in %q", layout[i], layout)
+		}
+		flushLiteral()
+		parts = append(parts, directive)
+	}
+	flushLiteral()
+	return func(t time.Time) string {
+		b := strings.Builder{}
+		for _, part := range parts {
+			b.WriteString(part(t))
+		}
+		return b.String()
+	}, nil
+}
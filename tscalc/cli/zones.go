@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	p "lib/tscalc/parse"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// parseZones parses the comma-separated list of the time zones, e.g. "UTC,America/New_York,Asia/Tokyo", or reads
+// them from the file given as "@path", one or more per line. In the file, the lines starting with # are comments.
+func parseZones(spec string) ([]*time.Location, error) {
+	if strings.HasPrefix(spec, "@") {
+		path := strings.TrimPrefix(spec, "@")
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(home, path[2:])
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lines := []string{}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		spec = strings.Join(lines, ",")
+	}
+	zones := []*time.Location{}
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		loc, err := loadLocation(name)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, loc)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no time zones in %q", spec)
+	}
+	return zones, nil
+}
+
+// formatZones prints the timestamp in every zone, one row per zone with the zone, the time in the output format,
+// the offset, the DST flag and the day of the week.
+func formatZones(t p.IsoTimeNode, zones []*time.Location, opts options) (string, error) {
+	b := strings.Builder{}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, loc := range zones {
+		opts.loc = loc
+		local := t.Time.In(loc)
+		res, err := formatResult(p.IsoTimeNode{Time: t.Time}, opts)
+		if err != nil {
+			return "", err
+		}
+		dst := ""
+		if local.IsDST() {
+			dst = "DST"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", loc, res, local.Format("-07:00"), local.Format("Mon"), dst)
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	// The empty DST column leaves the padding after the day of the week.
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
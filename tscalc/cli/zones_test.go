@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseZones(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zones")
+	assert.NoError(t, os.WriteFile(path, []byte("# team\nEurope/Warsaw\n\nAsia/Tokyo, UTC\n"), 0o600))
+	for _, tc := range []struct {
+		spec     string
+		expected []string
+	}{
+		{"UTC,America/New_York", []string{"UTC", "America/New_York"}},
+		{" Asia/Tokyo , ", []string{"Asia/Tokyo"}},
+		{"@" + path, []string{"Europe/Warsaw", "Asia/Tokyo", "UTC"}},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			zones, err := parseZones(tc.spec)
+			assert.NoError(t, err)
+			names := []string{}
+			for _, zone := range zones {
+				names = append(names, zone.String())
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestParseZonesErrors(t *testing.T) {
+	_, err := parseZones("UTC,Mars/Base")
+	assert.EqualError(t, err, "unknown time zone Mars/Base")
+	_, err = parseZones(",")
+	assert.EqualError(t, err, `no time zones in ","`)
+	_, err = parseZones("@" + filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestZonesTable(t *testing.T) {
+	nowFunc = func() time.Time {
+		return time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)
+	}
+	s := newSession()
+	var err error
+	s.zones, err = parseZones("UTC,America/New_York,Asia/Kolkata")
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"now", `UTC               2023-10-29T19:40:09+00:00  +00:00  Sun
+America/New_York  2023-10-29T15:40:09-04:00  -04:00  Sun  DST
+Asia/Kolkata      2023-10-30T01:10:09+05:30  +05:30  Mon`},
+		{"1698608409 + 1h as %H:%M", `UTC               20:40  +00:00  Sun
+America/New_York  16:40  -04:00  Sun  DST
+Asia/Kolkata      02:10  +05:30  Mon`},
+		{"now - 2023-10-29T19:00:00Z", "40m9s"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := s.handleLine(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnchorNode is a point in time named relative to the current time, like "yesterday", "start of week" or
+// "next monday 09:00". The weeks start on Monday.
+type AnchorNode struct {
+	// Kind is one of: today, yesterday, tomorrow, start, end, last, next.
+	Kind string
+	// Unit is the unit for "start of" and "end of": minute, hour, day, week, month or year.
+	Unit string
+	// Weekday is the day for "last" and "next".
+	Weekday time.Weekday
+	// HasClock is true if the time of the day is given, e.g. "tomorrow 09:00".
+	HasClock bool
+	Hour     int
+	Minute   int
+	Second   int
+	Cur      Cursor
+}
+
+func (n AnchorNode) Cursor() Cursor {
+	return n.Cur
+}
+
+func (n AnchorNode) String() string {
+	var s string
+	switch n.Kind {
+	case "start", "end":
+		s = fmt.Sprintf("%s of %s", n.Kind, n.Unit)
+	case "last", "next":
+		s = fmt.Sprintf("%s %s", n.Kind, strings.ToLower(n.Weekday.String()))
+	default:
+		s = n.Kind
+	}
+	if n.HasClock {
+		s += fmt.Sprintf(" %02d:%02d:%02d", n.Hour, n.Minute, n.Second)
+	}
+	return s
+}
+
+// Resolve returns the time of the anchor relative to the current time in the location.
+func (n AnchorNode) Resolve(now time.Time, loc *time.Location) IsoTimeNode {
+	now = now.In(loc)
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	var t time.Time
+	switch n.Kind {
+	case "today":
+		t = midnight
+	case "yesterday":
+		t = midnight.AddDate(0, 0, -1)
+	case "tomorrow":
+		t = midnight.AddDate(0, 0, 1)
+	case "start":
+		t, _ = UnitBounds(now, n.Unit)
+	case "end":
+		_, next := UnitBounds(now, n.Unit)
+		t = next.Add(-time.Nanosecond)
+	case "last":
+		days := (int(now.Weekday()) - int(n.Weekday) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		t = midnight.AddDate(0, 0, -days)
+	case "next":
+		days := (int(n.Weekday) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		t = midnight.AddDate(0, 0, days)
+	}
+	if n.HasClock {
+		t = time.Date(t.Year(), t.Month(), t.Day(), n.Hour, n.Minute, n.Second, 0, loc)
+	}
+	return IsoTimeNode{Time: t, Format: "anchor", Cur: n.Cur}
+}
+
+// UnitBounds returns the start of the unit (minute, hour, day, week, month or year) containing the time, and
+// the start of the next one.
+func UnitBounds(t time.Time, unit string) (time.Time, time.Time) {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case "minute":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc), time.Date(y, m, d, t.Hour(), t.Minute()+1, 0, 0, loc)
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, loc), time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+	case "week":
+		monday := d - (int(t.Weekday())+6)%7
+		return time.Date(y, m, monday, 0, 0, 0, 0, loc), time.Date(y, m, monday+7, 0, 0, 0, 0, loc)
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc), time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc), time.Date(y+1, 1, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, loc), time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+type anchorStr struct{}
+
+// Anchor parses the names of points in time relative to now: "today", "yesterday", "tomorrow",
+// "start of <unit>", "end of <unit>", "last <weekday>" and "next <weekday>", optionally followed by the time of
+// the day, e.g. "next monday 09:00".
+var Anchor = anchorStr{}
+
+func (p anchorStr) String() string {
+	return "<anchor>"
+}
+
+var anchorPattern = regexp.MustCompile(`^(?i)(?:(today|yesterday|tomorrow)|(start|end) of (?:the )?(minute|hour|day|week|month|year)|(last|next) (monday|tuesday|wednesday|thursday|friday|saturday|sunday))\b(?:\s+(\d{1,2}):(\d{2})(?::(\d{2}))?)?`)
+
+func (p anchorStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("Anchor on: %s$", input)
+	groups := anchorPattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	node := AnchorNode{Cur: input}
+	switch {
+	case groups[1] != "":
+		node.Kind = strings.ToLower(groups[1])
+	case groups[2] != "":
+		node.Kind = strings.ToLower(groups[2])
+		node.Unit = strings.ToLower(groups[3])
+	default:
+		node.Kind = strings.ToLower(groups[4])
+		node.Weekday = weekdays[strings.ToLower(groups[5])]
+	}
+	if groups[6] != "" {
+		node.HasClock = true
+		node.Hour, _ = strconv.Atoi(groups[6])
+		node.Minute, _ = strconv.Atoi(groups[7])
+		if groups[8] != "" {
+			node.Second, _ = strconv.Atoi(groups[8])
+		}
+		if node.Hour > 23 || node.Minute > 59 || node.Second > 59 {
+			return nil, input, fmt.Errorf("invalid time of the day in %s", groups[0])
+		}
+	}
+	return node, input.Advance(len(groups[0])), nil
+}
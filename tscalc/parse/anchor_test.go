@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnchor(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	// Wednesday.
+	now := time.Date(2023, 11, 15, 13, 14, 15, 16, warsaw)
+	for _, tc := range []struct {
+		input    string
+		expected time.Time
+	}{
+		{"today", time.Date(2023, 11, 15, 0, 0, 0, 0, warsaw)},
+		{"yesterday", time.Date(2023, 11, 14, 0, 0, 0, 0, warsaw)},
+		{"Tomorrow 09:30", time.Date(2023, 11, 16, 9, 30, 0, 0, warsaw)},
+		{"start of minute", time.Date(2023, 11, 15, 13, 14, 0, 0, warsaw)},
+		{"start of hour", time.Date(2023, 11, 15, 13, 0, 0, 0, warsaw)},
+		{"start of day", time.Date(2023, 11, 15, 0, 0, 0, 0, warsaw)},
+		{"start of week", time.Date(2023, 11, 13, 0, 0, 0, 0, warsaw)},
+		{"start of the month", time.Date(2023, 11, 1, 0, 0, 0, 0, warsaw)},
+		{"start of year", time.Date(2023, 1, 1, 0, 0, 0, 0, warsaw)},
+		{"end of month", time.Date(2023, 11, 30, 23, 59, 59, 999_999_999, warsaw)},
+		{"end of day", time.Date(2023, 11, 15, 23, 59, 59, 999_999_999, warsaw)},
+		{"last friday", time.Date(2023, 11, 10, 0, 0, 0, 0, warsaw)},
+		{"last wednesday", time.Date(2023, 11, 8, 0, 0, 0, 0, warsaw)},
+		{"next monday 09:00", time.Date(2023, 11, 20, 9, 0, 0, 0, warsaw)},
+		{"next wednesday 23:59:59", time.Date(2023, 11, 22, 23, 59, 59, 0, warsaw)},
+		{"next thursday", time.Date(2023, 11, 16, 0, 0, 0, 0, warsaw)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := Anchor.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			actual := node.(AnchorNode).Resolve(now, warsaw).Time
+			assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+		})
+	}
+}
+
+func TestAnchorAcrossDST(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	// DST ends on Sunday 2023-10-29, so the week has one hour more.
+	now := time.Date(2023, 10, 27, 12, 0, 0, 0, warsaw)
+	node, _, err := Anchor.Parse(NewCursor("end of week"))
+	assert.NoError(t, err)
+	actual := node.(AnchorNode).Resolve(now, warsaw).Time
+	assert.Equal(t, "2023-10-29T23:59:59.999999999+01:00", actual.Format(isoFormat))
+}
+
+func TestAnchorNoMatch(t *testing.T) {
+	for _, input := range []string{"todays", "start of decade", "next monthly", "now"} {
+		t.Run(input, func(t *testing.T) {
+			node, rest, err := Anchor.Parse(NewCursor(input))
+			assert.NoError(t, err)
+			assert.Nil(t, node)
+			assert.Equal(t, input, rest.String())
+		})
+	}
+}
+
+func TestAnchorInvalidClock(t *testing.T) {
+	_, _, err := Anchor.Parse(NewCursor("tomorrow 25:00"))
+	assert.Error(t, err)
+}
@@ -18,6 +18,25 @@ type CursorError interface {
 	Node
 }
 
+// parseError is the error of the parser that does not tell where it happened. It points at the start of the term
+// that failed.
+type parseError struct {
+	err error
+	cur Cursor
+}
+
+func (e parseError) Error() string {
+	return e.err.Error()
+}
+
+func (e parseError) Unwrap() error {
+	return e.err
+}
+
+func (e parseError) Cursor() Cursor {
+	return e.cur
+}
+
 // Parser is not a pure function because there might be parsers that will have some minimal state.
 type Parser interface {
 	// Parse returns the node if found, the remaining string and the error if any. If the node is not found, then
@@ -79,6 +98,9 @@ func FirstOf(parsers ...Parser) Parser {
 		for i, p := range parsers {
 			Logf("FirstOf[%d/%d]: %s", i+1, len(parsers), p)
 			node, rest, err := p.Parse(input)
+			if _, ok := err.(CursorError); err != nil && !ok {
+				err = parseError{err: err, cur: input}
+			}
 			if err != nil || node != nil {
 				if node != nil {
 					Logf("FirstOf[%d/%d] match, rest: %s$", i+1, len(parsers), rest)
@@ -96,6 +118,23 @@ func FirstOf(parsers ...Parser) Parser {
 	return FuncParser{Fn: pf, Name: name}
 }
 
+// Map transforms the node returned by the parser with the function. It is used to build the syntax tree from
+// the nodes returned by the generic parsers like Sequence. An error returned by the function stops the parsing.
+func Map(parser Parser, fn func(Node) (Node, error)) Parser {
+	pf := func(input Cursor) (Node, Cursor, error) {
+		node, rest, err := parser.Parse(input)
+		if err != nil || node == nil {
+			return node, rest, err
+		}
+		mapped, err := fn(node)
+		if err != nil {
+			return nil, input, err
+		}
+		return mapped, rest, nil
+	}
+	return FuncParser{Fn: pf, Name: fmt.Sprint(parser)}
+}
+
 // RefStr is used to build recursive parsers.
 type RefStr struct {
 	Parser Parser
@@ -0,0 +1,39 @@
+package parse
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	count := Map(Repeated(Literal("x")), func(n Node) (Node, error) {
+		return LiteralNode{Literal: fmt.Sprint(n.(SequenceNode).Len())}, nil
+	})
+	node, rest, err := count.Parse(NewCursor("xxxy"))
+	assert.NoError(t, err)
+	assert.Equal(t, "y", rest.String())
+	assert.Equal(t, `"3"`, fmt.Sprint(node))
+}
+
+func TestMapNoMatch(t *testing.T) {
+	called := false
+	parser := Map(Literal("x"), func(n Node) (Node, error) {
+		called = true
+		return n, nil
+	})
+	node, rest, err := parser.Parse(NewCursor("y"))
+	assert.NoError(t, err)
+	assert.Nil(t, node)
+	assert.Equal(t, "y", rest.String())
+	assert.False(t, called)
+}
+
+func TestMapError(t *testing.T) {
+	parser := Map(Literal("x"), func(n Node) (Node, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	_, _, err := parser.Parse(NewCursor("x"))
+	assert.EqualError(t, err, "boom")
+}
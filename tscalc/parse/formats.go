@@ -0,0 +1,377 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartialTimeNode is a timestamp with some parts missing, e.g. the syslog timestamp has no year and no time zone,
+// "19:40" has no date and "2023-10-29" no time of the day. The missing parts are resolved against the current time in
+// the selected location.
+type PartialTimeNode struct {
+	Year       int
+	Month      time.Month
+	Day        int
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+	HasYear    bool
+	// HasDate is false for the time of the day alone, then the date is today.
+	HasDate bool
+	// HasClock is false for the date alone, then the time is midnight.
+	HasClock bool
+	// Loc is the time zone of the timestamp, nil if the timestamp has no zone.
+	Loc *time.Location
+	// Format is the name of the input format the time was parsed from.
+	Format string
+	Cur    Cursor
+}
+
+func (n PartialTimeNode) Cursor() Cursor {
+	return n.Cur
+}
+
+// String prints the known parts of the timestamp, the missing year is printed as "????" and the missing date as
+// "????-??-??". The date alone is printed without the time.
+func (n PartialTimeNode) String() string {
+	year := "????"
+	if n.HasYear {
+		year = fmt.Sprintf("%04d", n.Year)
+	}
+	s := fmt.Sprintf("%s-%02d-%02d", year, n.Month, n.Day)
+	if !n.HasDate {
+		s = "????-??-??"
+	}
+	if n.HasClock {
+		s += fmt.Sprintf("T%02d:%02d:%02d", n.Hour, n.Minute, n.Second)
+	}
+	if n.Nanosecond != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", n.Nanosecond), "0")
+	}
+	if n.Loc != nil {
+		s += " " + n.Loc.String()
+	}
+	return s
+}
+
+// Inferred returns the names of the parts that are resolved against the current time and the selected location.
+func (n PartialTimeNode) Inferred() []string {
+	inferred := []string{}
+	switch {
+	case !n.HasDate:
+		inferred = append(inferred, "date")
+	case !n.HasYear:
+		inferred = append(inferred, "year")
+	}
+	if !n.HasClock {
+		inferred = append(inferred, "time")
+	}
+	if n.Loc == nil {
+		inferred = append(inferred, "zone")
+	}
+	return inferred
+}
+
+// Resolve fills the missing parts from the current time in the location. The missing zone is the location.
+// The missing year is the current year, unless the timestamp would be more than a day in the future, then it is
+// the previous year. This way the timestamps from the logs written in December are right when read in January.
+// The missing date is today and the missing time is midnight.
+func (n PartialTimeNode) Resolve(now time.Time, loc *time.Location) IsoTimeNode {
+	if n.Loc != nil {
+		loc = n.Loc
+	}
+	now = now.In(loc)
+	year, month, day := n.Year, n.Month, n.Day
+	if !n.HasDate {
+		year, month, day = now.Date()
+	} else if !n.HasYear {
+		year = now.Year()
+	}
+	t := time.Date(year, month, day, n.Hour, n.Minute, n.Second, n.Nanosecond, loc)
+	if n.HasDate && !n.HasYear && t.After(now.AddDate(0, 0, 1)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return IsoTimeNode{Time: t, Format: n.Format, Cur: n.Cur}
+}
+
+func partialFromTime(t time.Time) PartialTimeNode {
+	return PartialTimeNode{
+		Year:       t.Year(),
+		Month:      t.Month(),
+		Day:        t.Day(),
+		Hour:       t.Hour(),
+		Minute:     t.Minute(),
+		Second:     t.Second(),
+		Nanosecond: t.Nanosecond(),
+		HasYear:    true,
+		HasDate:    true,
+		HasClock:   true,
+	}
+}
+
+const months = `(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)`
+
+type sqlTimeStr struct{}
+
+// SqlTime parses the timestamps printed by the databases, e.g. "2023-10-29 19:40:09.123" or
+// "2023-10-29 19:40:09+00". The zone and the seconds are optional. "T" can be used instead of the space. The zone
+// followed by a unit, like "-10m", is not a zone but the period after the time.
+var SqlTime = sqlTimeStr{}
+
+func (p sqlTimeStr) String() string {
+	return "<sql-time>"
+}
+
+var sqlTimePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[ T](\d{2}:\d{2})(:\d{2}(?:\.\d+)?)?(Z|[+-]\d{2}(?::?\d{2})?)?`)
+var periodAfterZone = regexp.MustCompile(`^[\w.µ]`)
+
+func (p sqlTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("SqlTime on: %s$", input)
+	groups := sqlTimePattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	match, clock, zone := groups[0], groups[2]+groups[3], groups[4]
+	// The zone followed by a unit is the period added to the time, e.g. "2023-10-29 10:00-10m".
+	if zone != "" && periodAfterZone.MatchString(input.String()[len(match):]) {
+		match, zone = strings.TrimSuffix(match, zone), ""
+	}
+	rest := input.Advance(len(match))
+	if groups[3] == "" {
+		clock += ":00"
+	}
+	if zone == "" {
+		t, err := time.Parse("2006-01-02 15:04:05", groups[1]+" "+clock)
+		if err != nil {
+			return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
+		}
+		partial := partialFromTime(t)
+		partial.Format, partial.Cur = "sql", input
+		return partial, rest, nil
+	}
+	// Normalize the zone to +hh:mm.
+	if zone != "Z" {
+		zone = strings.Replace(zone, ":", "", 1)
+		if len(zone) == 3 {
+			zone += "00"
+		}
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	t, err := time.Parse(time.RFC3339, groups[1]+"T"+clock+zone)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
+	}
+	return IsoTimeNode{Time: t, Format: "sql", Cur: input}, rest, nil
+}
+
+type dateOnlyStr struct{}
+
+// DateOnly parses the date without the time, e.g. "2023-10-29". The time is midnight in the selected location.
+var DateOnly = dateOnlyStr{}
+
+func (p dateOnlyStr) String() string {
+	return "<date>"
+}
+
+var dateOnlyPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\b`)
+
+func (p dateOnlyStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("DateOnly on: %s$", input)
+	match := dateOnlyPattern.FindString(input.String())
+	if match == "" {
+		return nil, input, nil
+	}
+	t, err := time.Parse("2006-01-02", match)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
+	}
+	partial := partialFromTime(t)
+	partial.HasClock, partial.Format, partial.Cur = false, "date", input
+	return partial, input.Advance(len(match)), nil
+}
+
+type timeOnlyStr struct{}
+
+// TimeOnly parses the time of the day without the date, e.g. "19:40", "19:40:09" or "19:40:09.5". The date is today
+// in the selected location.
+var TimeOnly = timeOnlyStr{}
+
+func (p timeOnlyStr) String() string {
+	return "<time-of-day>"
+}
+
+var timeOnlyPattern = regexp.MustCompile(`^\d{1,2}:\d{2}(?::\d{2}(?:\.\d+)?)?\b`)
+
+func (p timeOnlyStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("TimeOnly on: %s$", input)
+	match := timeOnlyPattern.FindString(input.String())
+	if match == "" {
+		return nil, input, nil
+	}
+	clock := match
+	if strings.Count(clock, ":") == 1 {
+		clock += ":00"
+	}
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
+	}
+	partial := partialFromTime(t)
+	partial.HasYear, partial.HasDate, partial.Format, partial.Cur = false, false, "time", input
+	return partial, input.Advance(len(match)), nil
+}
+
+type clfTimeStr struct{}
+
+// ClfTime parses the timestamps from the Common Log Format used by nginx and Apache, e.g.
+// "[29/Oct/2023:19:40:09 +0000]". The brackets are optional.
+var ClfTime = clfTimeStr{}
+
+func (p clfTimeStr) String() string {
+	return "<clf-time>"
+}
+
+var clfTimePattern = regexp.MustCompile(`^(\[)?(\d{2}/` + months + `/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})(\])?`)
+
+func (p clfTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("ClfTime on: %s$", input)
+	groups := clfTimePattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	// Either both brackets or none.
+	if (groups[1] == "") != (groups[3] == "") {
+		return nil, input, nil
+	}
+	t, err := time.Parse("02/Jan/2006:15:04:05 -0700", groups[2])
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", groups[0], err)
+	}
+	return IsoTimeNode{Time: t, Format: "clf", Cur: input}, input.Advance(len(groups[0])), nil
+}
+
+type rfc1123TimeStr struct{}
+
+// Rfc1123Time parses the timestamps used in HTTP headers and emails, e.g. "Sun, 29 Oct 2023 19:40:09 GMT" or
+// "Sun, 29 Oct 2023 19:40:09 +0100". The week day is optional. Only GMT, UTC, UT and Z zone abbreviations are
+// accepted, because the other abbreviations are ambiguous.
+var Rfc1123Time = rfc1123TimeStr{}
+
+func (p rfc1123TimeStr) String() string {
+	return "<rfc1123-time>"
+}
+
+var rfc1123TimePattern = regexp.MustCompile(`^(?:(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun), )?(\d{1,2} ` + months + ` \d{4} \d{2}:\d{2}:\d{2}) (GMT|UTC|UT|Z|[+-]\d{4})`)
+
+func (p rfc1123TimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("Rfc1123Time on: %s$", input)
+	groups := rfc1123TimePattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	zone := groups[2]
+	if !strings.HasPrefix(zone, "+") && !strings.HasPrefix(zone, "-") {
+		zone = "+0000"
+	}
+	t, err := time.Parse("2 Jan 2006 15:04:05 -0700", groups[1]+" "+zone)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", groups[0], err)
+	}
+	return IsoTimeNode{Time: t, Format: "rfc1123", Cur: input}, input.Advance(len(groups[0])), nil
+}
+
+type syslogTimeStr struct{}
+
+// SyslogTime parses the timestamps of the traditional syslog, e.g. "Oct 29 19:40:09". The timestamp has no year and
+// no zone, so they are resolved against the current time in the selected location.
+var SyslogTime = syslogTimeStr{}
+
+func (p syslogTimeStr) String() string {
+	return "<syslog-time>"
+}
+
+var syslogTimePattern = regexp.MustCompile(`^` + months + ` [ \d]\d \d{2}:\d{2}:\d{2}(?:\.\d+)?`)
+
+func (p syslogTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("SyslogTime on: %s$", input)
+	match := syslogTimePattern.FindString(input.String())
+	if match == "" {
+		return nil, input, nil
+	}
+	t, err := time.Parse(time.Stamp, match)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
+	}
+	partial := partialFromTime(t)
+	partial.HasYear, partial.Format, partial.Cur = false, "syslog", input
+	return partial, input.Advance(len(match)), nil
+}
+
+type pythonTimeStr struct{}
+
+// PythonTime parses the repr of Python datetime, e.g. "datetime.datetime(2023, 10, 29, 19, 40, 9)". The time zone
+// is recognized only if it is UTC, otherwise the timestamp is in the selected location.
+var PythonTime = pythonTimeStr{}
+
+func (p pythonTimeStr) String() string {
+	return "<python-time>"
+}
+
+var pythonTimePattern = regexp.MustCompile(`^datetime\.datetime\(\s*(\d+(?:\s*,\s*\d+){2,6})\s*(?:,\s*tzinfo\s*=\s*(datetime\.timezone\.utc|<UTC>|tzutc\(\)|zoneinfo\.ZoneInfo\(key='UTC'\))\s*)?\)`)
+
+func (p pythonTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("PythonTime on: %s$", input)
+	groups := pythonTimePattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	// year, month, day, hour, minute, second, microsecond
+	values := make([]int, 7)
+	for i, s := range strings.Split(groups[1], ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, input, fmt.Errorf("error while parsing %s: %w", groups[0], err)
+		}
+		values[i] = v
+	}
+	// time.Date would roll the values over, e.g. the 13th month is January of the next year.
+	for i, field := range []struct {
+		name     string
+		min, max int
+	}{
+		{"month", 1, 12},
+		{"day", 1, time.Date(values[0], time.Month(values[1])+1, 0, 0, 0, 0, 0, time.UTC).Day()},
+		{"hour", 0, 23},
+		{"minute", 0, 59},
+		{"second", 0, 59},
+		{"microsecond", 0, 999_999},
+	} {
+		if value := values[i+1]; value < field.min || value > field.max {
+			return nil, input, fmt.Errorf("error while parsing %s: %s %d out of range", groups[0], field.name, value)
+		}
+	}
+	partial := PartialTimeNode{
+		Year:       values[0],
+		Month:      time.Month(values[1]),
+		Day:        values[2],
+		Hour:       values[3],
+		Minute:     values[4],
+		Second:     values[5],
+		Nanosecond: values[6] * 1000,
+		HasYear:    true,
+		HasDate:    true,
+		HasClock:   true,
+		Format:     "python",
+		Cur:        input,
+	}
+	rest := input.Advance(len(groups[0]))
+	if groups[2] != "" {
+		partial.Loc = time.UTC
+		return partial.Resolve(time.Time{}, time.UTC), rest, nil
+	}
+	return partial, rest, nil
+}
@@ -0,0 +1,149 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseZonedFormats(t *testing.T) {
+	for _, tc := range []struct {
+		parser   Parser
+		input    string
+		expected time.Time
+	}{
+		{SqlTime, "2023-10-29 19:40:09+00", time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)},
+		{SqlTime, "2023-10-29 19:40:09.5+0100", time.Date(2023, 10, 29, 18, 40, 9, 500_000_000, time.UTC)},
+		{SqlTime, "2023-10-29 19:40:09-05:30", time.Date(2023, 10, 30, 1, 10, 9, 0, time.UTC)},
+		{SqlTime, "2023-10-29 19:40:09Z", time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)},
+		{ClfTime, "[29/Oct/2023:19:40:09 +0100]", time.Date(2023, 10, 29, 18, 40, 9, 0, time.UTC)},
+		{ClfTime, "29/Oct/2023:19:40:09 -0700", time.Date(2023, 10, 30, 2, 40, 9, 0, time.UTC)},
+		{Rfc1123Time, "Sun, 29 Oct 2023 19:40:09 GMT", time.Date(2023, 10, 29, 19, 40, 9, 0, time.UTC)},
+		{Rfc1123Time, "Sun, 29 Oct 2023 19:40:09 +0100", time.Date(2023, 10, 29, 18, 40, 9, 0, time.UTC)},
+		{Rfc1123Time, "1 Oct 2023 19:40:09 UTC", time.Date(2023, 10, 1, 19, 40, 9, 0, time.UTC)},
+		{PythonTime, "datetime.datetime(2023, 10, 29, 19, 40, 9, 123456, tzinfo=datetime.timezone.utc)", time.Date(2023, 10, 29, 19, 40, 9, 123_456_000, time.UTC)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			if assert.IsType(t, IsoTimeNode{}, node) {
+				actual := node.(IsoTimeNode).Time
+				assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSqlTimeBeforePeriod(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected Node
+		rest     string
+	}{
+		{"2023-10-29 10:00-10m", PartialTimeNode{}, "-10m"},
+		{"2023-10-29 10:00:00+15m", PartialTimeNode{}, "+15m"},
+		{"2023-10-29 10:00:00+0130s", PartialTimeNode{}, "+0130s"},
+		{"2023-10-29 10:00:00-10.5h", PartialTimeNode{}, "-10.5h"},
+		{"2023-10-29 10:00:00+01 + 1h", IsoTimeNode{}, " + 1h"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := SqlTime.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.IsType(t, tc.expected, node)
+			assert.Equal(t, tc.rest, rest.String())
+		})
+	}
+}
+
+func TestParsePartialFormats(t *testing.T) {
+	now := time.Date(2023, 11, 15, 12, 0, 0, 0, time.UTC)
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		parser   Parser
+		input    string
+		inferred []string
+		expected time.Time
+	}{
+		{SqlTime, "2023-10-29 19:40:09.123", []string{"zone"}, time.Date(2023, 10, 29, 19, 40, 9, 123_000_000, warsaw)},
+		{SqlTime, "2023-10-29T19:40:09", []string{"zone"}, time.Date(2023, 10, 29, 19, 40, 9, 0, warsaw)},
+		{SyslogTime, "Oct 29 19:40:09", []string{"year", "zone"}, time.Date(2023, 10, 29, 19, 40, 9, 0, warsaw)},
+		{SyslogTime, "Oct  1 19:40:09", []string{"year", "zone"}, time.Date(2023, 10, 1, 19, 40, 9, 0, warsaw)},
+		// December is in the future, so it is from the previous year.
+		{SyslogTime, "Dec 24 19:40:09", []string{"year", "zone"}, time.Date(2022, 12, 24, 19, 40, 9, 0, warsaw)},
+		{PythonTime, "datetime.datetime(2023, 10, 29, 19, 40)", []string{"zone"}, time.Date(2023, 10, 29, 19, 40, 0, 0, warsaw)},
+		{SqlTime, "2023-10-29 19:40", []string{"zone"}, time.Date(2023, 10, 29, 19, 40, 0, 0, warsaw)},
+		{DateOnly, "2023-10-29", []string{"time", "zone"}, time.Date(2023, 10, 29, 0, 0, 0, 0, warsaw)},
+		{TimeOnly, "19:40", []string{"date", "zone"}, time.Date(2023, 11, 15, 19, 40, 0, 0, warsaw)},
+		{TimeOnly, "9:05:09.5", []string{"date", "zone"}, time.Date(2023, 11, 15, 9, 5, 9, 500_000_000, warsaw)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			if assert.IsType(t, PartialTimeNode{}, node) {
+				partial := node.(PartialTimeNode)
+				assert.Equal(t, tc.inferred, partial.Inferred())
+				actual := partial.Resolve(now, warsaw).Time
+				assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTimeOnlyIsTodayInLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	// It is already the next day in Tokyo.
+	now := time.Date(2023, 11, 15, 23, 30, 0, 0, time.UTC)
+	node, _, err := TimeOnly.Parse(NewCursor("08:00"))
+	assert.NoError(t, err)
+	actual := node.(PartialTimeNode).Resolve(now, tokyo).Time
+	assert.Equal(t, "2023-11-16T08:00:00+09:00", actual.Format(isoFormat))
+}
+
+func TestParseFormatsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		parser Parser
+		input  string
+	}{
+		{DateOnly, "2023-02-30"},
+		{TimeOnly, "25:00"},
+		{SqlTime, "2023-10-29 19:60"},
+		{PythonTime, "datetime.datetime(2023, 13, 45)"},
+		{PythonTime, "datetime.datetime(2023, 2, 29)"},
+		{PythonTime, "datetime.datetime(2023, 10, 29, 24, 0)"},
+		{PythonTime, "datetime.datetime(2023, 10, 29, 19, 40, 9, 1000000)"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, _, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseFormatsNoMatch(t *testing.T) {
+	for _, tc := range []struct {
+		parser Parser
+		input  string
+	}{
+		{SqlTime, "2023-10-29"},
+		{ClfTime, "[29/Oct/2023:19:40:09 +0100"},
+		{Rfc1123Time, "Sun, 29 Oct 2023 19:40:09 EST"},
+		{SyslogTime, "Foo 29 19:40:09"},
+		{PythonTime, "datetime.datetime(2023)"},
+		{DateOnly, "2023-10-295"},
+		{DateOnly, "2023-10-29T19"},
+		{TimeOnly, "123:45"},
+		{TimeOnly, "19:4"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.Nil(t, node)
+			assert.Equal(t, tc.input, rest.String())
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package parse
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// idFormats are the names of the formats of the identifiers with the embedded creation time.
+var idFormats = map[string]bool{"uuid": true, "ulid": true, "ksuid": true, "objectid": true, "snowflake": true}
+
+// IsIdFormat reports if the format is an identifier with the embedded time, e.g. ULID, rather than a timestamp.
+func IsIdFormat(format string) bool {
+	return idFormats[format]
+}
+
+// hasLetter reports if the identifier is not all digits, so it is not mistaken for an epoch timestamp.
+var hasLetter = regexp.MustCompile(`[^0-9]`)
+
+type uuidTimeStr struct{}
+
+// UuidTime parses the time of the UUIDs of version 1, 6 and 7, e.g. "018b7c2e-7b40-7000-8000-000000000000".
+// The other versions have no time, so they are an error.
+var UuidTime = uuidTimeStr{}
+
+func (p uuidTimeStr) String() string {
+	return "<uuid>"
+}
+
+var uuidPattern = regexp.MustCompile(`^([0-9a-fA-F]{8})-([0-9a-fA-F]{4})-([0-9a-fA-F])([0-9a-fA-F]{3})-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+
+// gregorianOffset is the number of 100ns intervals between the start of the Gregorian calendar, used by UUIDv1,
+// and the Unix epoch.
+const gregorianOffset = 122_192_928_000_000_000
+
+func (p uuidTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("UuidTime on: %s$", input)
+	groups := uuidPattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	hex := func(s string) uint64 {
+		v, _ := strconv.ParseUint(s, 16, 64)
+		return v
+	}
+	// The groups of the time are in a different order in each version.
+	first, mid, version, last := hex(groups[1]), hex(groups[2]), groups[3], hex(groups[4])
+	var t time.Time
+	switch version {
+	case "1":
+		t = fromGregorian(int64(last<<48 | mid<<32 | first))
+	case "6":
+		t = fromGregorian(int64(first<<28 | mid<<12 | last))
+	case "7":
+		t = time.UnixMilli(int64(first<<16 | mid))
+	default:
+		return nil, input, fmt.Errorf("UUID %s is version %s, only versions 1, 6 and 7 have time", groups[0], version)
+	}
+	return IsoTimeNode{Time: t, Format: "uuid", Cur: input}, input.Advance(len(groups[0])), nil
+}
+
+// fromGregorian returns the time of the number of 100ns intervals since the start of the Gregorian calendar.
+func fromGregorian(ticks int64) time.Time {
+	ticks -= gregorianOffset
+	return time.Unix(ticks/10_000_000, ticks%10_000_000*100)
+}
+
+type ulidTimeStr struct{}
+
+// UlidTime parses the time of the ULIDs, e.g. "01HDXBVRQF0000000000000000". The first 10 characters are
+// the milliseconds since the epoch.
+var UlidTime = ulidTimeStr{}
+
+func (p ulidTimeStr) String() string {
+	return "<ulid>"
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}\b`)
+
+func (p ulidTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("UlidTime on: %s$", input)
+	match := ulidPattern.FindString(input.String())
+	if match == "" || !hasLetter.MatchString(match) {
+		return nil, input, nil
+	}
+	ms := int64(0)
+	for _, c := range strings.ToUpper(match[:10]) {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	return IsoTimeNode{Time: time.UnixMilli(ms), Format: "ulid", Cur: input}, input.Advance(len(match)), nil
+}
+
+type ksuidTimeStr struct{}
+
+// KsuidTime parses the time of the KSUIDs, e.g. "2XSZ4o2qFdS9LnFA0u1Hk0IVXz3". The time is the first 4 bytes,
+// the seconds since the KSUID epoch, 2014-05-13T16:53:20Z.
+var KsuidTime = ksuidTimeStr{}
+
+func (p ksuidTimeStr) String() string {
+	return "<ksuid>"
+}
+
+const (
+	base62     = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	ksuidEpoch = 1_400_000_000
+)
+
+var ksuidPattern = regexp.MustCompile(`^[0-9A-Za-z]{27}\b`)
+
+func (p ksuidTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("KsuidTime on: %s$", input)
+	match := ksuidPattern.FindString(input.String())
+	if match == "" || !hasLetter.MatchString(match) {
+		return nil, input, nil
+	}
+	n := new(big.Int)
+	for _, c := range match {
+		n.Mul(n, big.NewInt(62))
+		n.Add(n, big.NewInt(int64(strings.IndexRune(base62, c))))
+	}
+	// KSUID is 20 bytes, the first 4 of them are the time.
+	if n.BitLen() > 160 {
+		return nil, input, nil
+	}
+	sec := n.Rsh(n, 128).Int64()
+	return IsoTimeNode{Time: time.Unix(ksuidEpoch+sec, 0), Format: "ksuid", Cur: input}, input.Advance(len(match)), nil
+}
+
+type objectIdTimeStr struct{}
+
+// ObjectIdTime parses the time of the MongoDB ObjectIds, e.g. "653eaa0d1c9d440000a1b2c3". The first 4 bytes are
+// the seconds since the epoch.
+var ObjectIdTime = objectIdTimeStr{}
+
+func (p objectIdTimeStr) String() string {
+	return "<objectid>"
+}
+
+var objectIdPattern = regexp.MustCompile(`^[0-9a-fA-F]{24}\b`)
+
+func (p objectIdTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("ObjectIdTime on: %s$", input)
+	match := objectIdPattern.FindString(input.String())
+	if match == "" || !hasLetter.MatchString(match) {
+		return nil, input, nil
+	}
+	sec, _ := strconv.ParseInt(match[:8], 16, 64)
+	return IsoTimeNode{Time: time.Unix(sec, 0), Format: "objectid", Cur: input}, input.Advance(len(match)), nil
+}
+
+// The epochs of the popular snowflake identifiers.
+var (
+	TwitterEpoch = time.UnixMilli(1_288_834_974_657)
+	DiscordEpoch = time.UnixMilli(1_420_070_400_000)
+)
+
+type snowflakeTimeStr struct {
+	epoch time.Time
+}
+
+// SnowflakeTimeIn parses the time of the snowflake identifiers, e.g. "1541815603606036480", with the given epoch.
+// The time is the milliseconds since the epoch in the bits above the lowest 22. The snowflakes are plain numbers, so
+// only the numbers of 15 to 20 digits are snowflakes, the shorter ones are left to the other parsers.
+func SnowflakeTimeIn(epoch time.Time) Parser {
+	return snowflakeTimeStr{epoch: epoch}
+}
+
+func (p snowflakeTimeStr) String() string {
+	return "<snowflake>"
+}
+
+var snowflakePattern = regexp.MustCompile(`^\d{15,20}\b`)
+
+func (p snowflakeTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("SnowflakeTime on: %s$", input)
+	match := snowflakePattern.FindString(input.String())
+	if match == "" || strings.HasPrefix(input.String()[len(match):], ".") {
+		return nil, input, nil
+	}
+	id, err := strconv.ParseUint(match, 10, 64)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing snowflake %s: %w", match, err)
+	}
+	t := p.epoch.Add(time.Duration(id>>22) * time.Millisecond)
+	return IsoTimeNode{Time: t, Format: "snowflake", Cur: input}, input.Advance(len(match)), nil
+}
@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIds(t *testing.T) {
+	for _, tc := range []struct {
+		parser   Parser
+		input    string
+		expected time.Time
+	}{
+		{UuidTime, "c232ab00-9414-11ec-b3c8-9f6bdeced846", time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)},
+		{UuidTime, "1EC9414C-232A-6B00-B3C8-9F6BDECED846", time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)},
+		{UuidTime, "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)},
+		{UlidTime, "01ARZ3NDEKTSV4RRFFQ69G5FAV", time.Date(2016, 7, 30, 23, 54, 10, 259_000_000, time.UTC)},
+		{UlidTime, "01arz3ndektsv4rrffq69g5fav", time.Date(2016, 7, 30, 23, 54, 10, 259_000_000, time.UTC)},
+		{KsuidTime, "0ujtsYcgvSTl8PAuAdqWYSMnLOv", time.Date(2017, 10, 10, 4, 0, 47, 0, time.UTC)},
+		{ObjectIdTime, "653eaa0d1c9d440000a1b2c3", time.Date(2023, 10, 29, 18, 53, 1, 0, time.UTC)},
+		{SnowflakeTimeIn(DiscordEpoch), "175928847299117063", time.Date(2016, 4, 30, 11, 18, 25, 796_000_000, time.UTC)},
+		{SnowflakeTimeIn(TwitterEpoch), "1541815603606036480", time.Date(2022, 6, 28, 16, 7, 40, 105_000_000, time.UTC)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			if assert.IsType(t, IsoTimeNode{}, node) {
+				actual := node.(IsoTimeNode).Time
+				assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+				assert.True(t, IsIdFormat(node.(IsoTimeNode).Format))
+			}
+		})
+	}
+}
+
+func TestParseIdsNoMatch(t *testing.T) {
+	for _, tc := range []struct {
+		parser Parser
+		input  string
+	}{
+		// The numbers are epoch timestamps, not identifiers.
+		{UlidTime, "12345678901234567890123456"},
+		{ObjectIdTime, "123456789012345678901234"},
+		{KsuidTime, "123456789012345678901234567"},
+		// Too long.
+		{ObjectIdTime, "653eaa0d1c9d440000a1b2c3d"},
+		{UlidTime, "01ARZ3NDEKTSV4RRFFQ69G5FAV_1"},
+		{SnowflakeTimeIn(TwitterEpoch), "1698603564"},
+		{SnowflakeTimeIn(TwitterEpoch), "1541815603606036480.5"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, _, err := tc.parser.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.Nil(t, node)
+		})
+	}
+}
+
+func TestParseUuidWithoutTime(t *testing.T) {
+	_, _, err := UuidTime.Parse(NewCursor("f47ac10b-58cc-4372-a567-0e02b2c3d479"))
+	assert.EqualError(t, err, "UUID f47ac10b-58cc-4372-a567-0e02b2c3d479 is version 4, only versions 1, 6 and 7 have time")
+}
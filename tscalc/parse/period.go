@@ -0,0 +1,209 @@
+package parse
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeriodNode is a period of time. The calendar part (months and days) is kept separately from the fixed duration,
+// because the length of a month or a day depends on the timestamp it is added to.
+type PeriodNode struct {
+	Duration time.Duration
+	// Months is the calendar part of the period. A year is 12 months.
+	Months int
+	// Days is the calendar part of the period. A week is 7 days. A day is not always 24h because of DST.
+	Days int
+	// Since is the start of the period if the period is the difference of two timestamps, zero otherwise. It is used
+	// to tell the period in calendar terms, e.g. in months.
+	Since time.Time
+	Cur   Cursor
+}
+
+func (n PeriodNode) Cursor() Cursor {
+	return n.Cur
+}
+
+func (n PeriodNode) String() string {
+	if !n.IsCalendar() {
+		return fmt.Sprint(n.Duration)
+	}
+	if n.Months <= 0 && n.Days <= 0 && n.Duration <= 0 {
+		return "-" + n.Neg().String()
+	}
+	b := strings.Builder{}
+	if years := n.Months / 12; years != 0 {
+		fmt.Fprintf(&b, "%dy", years)
+	}
+	if months := n.Months % 12; months != 0 {
+		fmt.Fprintf(&b, "%dmo", months)
+	}
+	if n.Days != 0 {
+		fmt.Fprintf(&b, "%dd", n.Days)
+	}
+	if n.Duration != 0 {
+		b.WriteString(n.Duration.String())
+	}
+	return b.String()
+}
+
+// IsCalendar returns true if the period has the calendar part, i.e. its length depends on when it starts.
+func (n PeriodNode) IsCalendar() bool {
+	return n.Months != 0 || n.Days != 0
+}
+
+// IsAnchored returns true if the period is known to start at a particular time, see Since.
+func (n PeriodNode) IsAnchored() bool {
+	return !n.Since.IsZero()
+}
+
+func (n PeriodNode) Neg() PeriodNode {
+	n.Duration = -n.Duration
+	n.Months = -n.Months
+	n.Days = -n.Days
+	n.Since = time.Time{}
+	return n
+}
+
+// Add returns the sum of the periods. The calendar and the fixed parts are added separately.
+func (n PeriodNode) Add(other PeriodNode) PeriodNode {
+	n.Duration += other.Duration
+	n.Months += other.Months
+	n.Days += other.Days
+	n.Since = time.Time{}
+	return n
+}
+
+// AddTo adds the period to the time. The calendar part is added first, in the given location, so adding days
+// keeps the wall clock across DST changes, and months roll over like in time.AddDate.
+func (n PeriodNode) AddTo(t time.Time, loc *time.Location) time.Time {
+	if n.IsCalendar() {
+		t = t.In(loc).AddDate(0, n.Months, n.Days)
+	}
+	return t.Add(n.Duration)
+}
+
+type periodStr struct{}
+
+var Period = periodStr{}
+
+func (p periodStr) String() string {
+	return "<period>"
+}
+
+// The alternatives are ordered so that "mo" and "ms" are matched before "m".
+var periodPattern = regexp.MustCompile(`^(?:\d+(?:\.\d+)?(?:mo|ms|us|µs|ns|[hmsdwy]))+`)
+var periodComponentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)(mo|ms|us|µs|ns|[hmsdwy])`)
+
+func (p periodStr) Parse(input Cursor) (Node, Cursor, error) {
+	indices := periodPattern.FindStringSubmatchIndex(input.String())
+	if indices == nil {
+		return nil, input, nil
+	}
+	match := input.String()[indices[0]:indices[1]]
+	node := PeriodNode{Cur: input}
+	for _, component := range periodComponentPattern.FindAllStringSubmatch(match, -1) {
+		unit := component[2]
+		switch unit {
+		case "y", "mo", "w", "d":
+			value, err := strconv.Atoi(component[1])
+			if err != nil {
+				return nil, input, fmt.Errorf("calendar period %s must be an integer: %w", component[0], err)
+			}
+			switch unit {
+			case "y":
+				node.Months += 12 * value
+			case "mo":
+				node.Months += value
+			case "w":
+				node.Days += 7 * value
+			case "d":
+				node.Days += value
+			}
+		default:
+			d, err := parseFixed(component[1], unit)
+			if err != nil {
+				return nil, input, fmt.Errorf("error while parsing period %s: %w", match, err)
+			}
+			node.Duration += d
+		}
+	}
+	rest := input.Advance(indices[1])
+	return node, rest, nil
+}
+
+// parseFixed returns the duration of the value in the unit, which can have the decimal fraction, e.g. "1.5" and "s".
+// The fractions of a nanosecond are rejected rather than truncated, so "0.1ns" is not the zero period.
+func parseFixed(value, unit string) (time.Duration, error) {
+	// time.ParseDuration handles the decimal values and the sub-second units.
+	d, err := time.ParseDuration(value + unit)
+	if err != nil {
+		return 0, err
+	}
+	exact, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return 0, fmt.Errorf("invalid number %s", value)
+	}
+	size, _ := time.ParseDuration("1" + unit)
+	if !exact.Mul(exact, big.NewRat(int64(size), 1)).IsInt() {
+		return 0, fmt.Errorf("the fraction is finer than a nanosecond")
+	}
+	return d, nil
+}
+
+type isoDurationStr struct{}
+
+// IsoDuration parses ISO 8601 duration, e.g. P1Y2M3DT4H5M6.5S or PT1H30M. Years, months, weeks and days are the
+// calendar part of the period. Only the time part can have fractions.
+var IsoDuration = isoDurationStr{}
+
+func (p isoDurationStr) String() string {
+	return "<iso-duration>"
+}
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?`)
+
+func (p isoDurationStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("IsoDuration on: %s$", input)
+	groups := isoDurationPattern.FindStringSubmatch(input.String())
+	// "P" alone or "PT" alone is not a duration.
+	if groups == nil || strings.TrimRight(groups[0], "T") == "P" {
+		return nil, input, nil
+	}
+	node := PeriodNode{Cur: input}
+	calendar := []struct {
+		group  string
+		months int
+		days   int
+	}{
+		{groups[1], 12, 0},
+		{groups[2], 1, 0},
+		{groups[3], 0, 7},
+		{groups[4], 0, 1},
+	}
+	for _, c := range calendar {
+		if c.group == "" {
+			continue
+		}
+		value, err := strconv.Atoi(c.group)
+		if err != nil {
+			return nil, input, fmt.Errorf("error while parsing duration %s: %w", groups[0], err)
+		}
+		node.Months += c.months * value
+		node.Days += c.days * value
+	}
+	for i, unit := range []string{"h", "m", "s"} {
+		if groups[5+i] == "" {
+			continue
+		}
+		d, err := parseFixed(groups[5+i], unit)
+		if err != nil {
+			return nil, input, fmt.Errorf("error while parsing duration %s: %w", groups[0], err)
+		}
+		node.Duration += d
+	}
+	return node, input.Advance(len(groups[0])), nil
+}
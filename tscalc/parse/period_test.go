@@ -30,3 +30,129 @@ func TestFormatPeriod(t *testing.T) {
 	}
 
 }
+
+func TestParseCalendarPeriod(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected PeriodNode
+		str      string
+	}{
+		{"3d", PeriodNode{Days: 3}, "3d"},
+		{"2w", PeriodNode{Days: 14}, "14d"},
+		{"1mo", PeriodNode{Months: 1}, "1mo"},
+		{"1y2mo", PeriodNode{Months: 14}, "1y2mo"},
+		{"1d12h", PeriodNode{Days: 1, Duration: 12 * time.Hour}, "1d12h0m0s"},
+		{"1mo1m", PeriodNode{Months: 1, Duration: time.Minute}, "1mo1m0s"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := Period.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			period := node.(PeriodNode)
+			period.Cur = Cursor{}
+			assert.Equal(t, tc.expected, period)
+			assert.Equal(t, tc.str, fmt.Sprint(period))
+		})
+	}
+}
+
+func TestFormatNegativeCalendarPeriod(t *testing.T) {
+	assert.Equal(t, "-1y1d1h0m0s", fmt.Sprint(PeriodNode{Months: 12, Days: 1, Duration: time.Hour}.Neg()))
+	assert.Equal(t, "1mo-1d", fmt.Sprint(PeriodNode{Months: 1, Days: -1}))
+}
+
+func TestAddCalendarPeriod(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	for _, tc := range []struct {
+		start    time.Time
+		period   PeriodNode
+		loc      *time.Location
+		expected time.Time
+	}{
+		{time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC), PeriodNode{Months: 1}, time.UTC, time.Date(2023, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), PeriodNode{Months: 12}, time.UTC, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// DST ends on 2023-10-29 in Warsaw, so the day has 25 hours.
+		{time.Date(2023, 10, 28, 12, 0, 0, 0, warsaw), PeriodNode{Days: 1}, warsaw, time.Date(2023, 10, 29, 12, 0, 0, 0, warsaw)},
+		{time.Date(2023, 10, 28, 12, 0, 0, 0, warsaw), PeriodNode{Duration: 24 * time.Hour}, warsaw, time.Date(2023, 10, 29, 11, 0, 0, 0, warsaw)},
+	} {
+		t.Run(fmt.Sprintf("%s + %s", tc.start, tc.period), func(t *testing.T) {
+			actual := tc.period.AddTo(tc.start, tc.loc)
+			assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+		})
+	}
+}
+
+func TestParseSubSecondPeriod(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"250ms", 250 * time.Millisecond},
+		{"1.5s", 1500 * time.Millisecond},
+		{"10us", 10 * time.Microsecond},
+		{"10µs", 10 * time.Microsecond},
+		{"7ns", 7 * time.Nanosecond},
+		{"1m30s500ms", 90*time.Second + 500*time.Millisecond},
+		{"0.5h", 30 * time.Minute},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := Period.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			assert.Equal(t, tc.expected, node.(PeriodNode).Duration)
+		})
+	}
+}
+
+func TestParseSubNanosecondPeriod(t *testing.T) {
+	for _, input := range []string{"0.1ns", "1.5ns", "0.0001us", "1.0000000001s", "0.0000000000001h"} {
+		t.Run(input, func(t *testing.T) {
+			_, _, err := Period.Parse(NewCursor(input))
+			assert.ErrorContains(t, err, "the fraction is finer than a nanosecond")
+		})
+	}
+	_, _, err := IsoDuration.Parse(NewCursor("PT0.0000000001S"))
+	assert.ErrorContains(t, err, "the fraction is finer than a nanosecond")
+	node, _, err := Period.Parse(NewCursor("1.000000001s"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second+time.Nanosecond, node.(PeriodNode).Duration)
+}
+
+func TestParseDecimalCalendarPeriod(t *testing.T) {
+	_, _, err := Period.Parse(NewCursor("1.5d"))
+	assert.Error(t, err)
+}
+
+func TestParseIsoDuration(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected PeriodNode
+	}{
+		{"PT1H30M", PeriodNode{Duration: 90 * time.Minute}},
+		{"P1Y2M3W4DT5H6M7.5S", PeriodNode{Months: 14, Days: 25, Duration: 5*time.Hour + 6*time.Minute + 7500*time.Millisecond}},
+		{"P396DT1H18M7S", PeriodNode{Days: 396, Duration: time.Hour + 18*time.Minute + 7*time.Second}},
+		{"PT0S", PeriodNode{}},
+		{"P1D", PeriodNode{Days: 1}},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := IsoDuration.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			period := node.(PeriodNode)
+			period.Cur = Cursor{}
+			assert.Equal(t, tc.expected, period)
+		})
+	}
+}
+
+func TestParseIsoDurationNoMatch(t *testing.T) {
+	for _, input := range []string{"P", "PT", "1h", "Pony"} {
+		t.Run(input, func(t *testing.T) {
+			node, rest, err := IsoDuration.Parse(NewCursor(input))
+			assert.NoError(t, err)
+			assert.Nil(t, node)
+			assert.Equal(t, input, rest.String())
+		})
+	}
+}
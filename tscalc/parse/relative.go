@@ -0,0 +1,88 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RelativeTimeNode is a point in time given as the period before or after the current time, like "3 hours ago" or
+// "in 2 days".
+type RelativeTimeNode struct {
+	Period PeriodNode
+	// Ago is true if the time is before now.
+	Ago bool
+	Cur Cursor
+}
+
+func (n RelativeTimeNode) Cursor() Cursor {
+	return n.Cur
+}
+
+func (n RelativeTimeNode) String() string {
+	if n.Ago {
+		return n.Period.String() + " ago"
+	}
+	return "in " + n.Period.String()
+}
+
+// Resolve returns the time of the period before or after the current time. The calendar part of the period is
+// added in the location.
+func (n RelativeTimeNode) Resolve(now time.Time, loc *time.Location) IsoTimeNode {
+	period := n.Period
+	if n.Ago {
+		period = period.Neg()
+	}
+	return IsoTimeNode{Time: period.AddTo(now, loc), Format: "relative", Cur: n.Cur}
+}
+
+type relativeTimeStr struct{}
+
+// RelativeTime parses the periods before and after the current time: "<period> ago" and "in <period>". The periods
+// are like "2h30m" or with the units spelled out, e.g. "3 hours" or "1 day 2 hours".
+var RelativeTime = relativeTimeStr{}
+
+func (p relativeTimeStr) String() string {
+	return "<relative-time>"
+}
+
+// The long names are before the short units, so "months" is not matched as "m".
+const relativeComponent = `(\d+(?:\.\d+)?)\s*(years?|months?|weeks?|days?|hours?|minutes?|mins?|seconds?|secs?|mo|ms|us|µs|ns|[hmsdwy])`
+
+var relativePattern = regexp.MustCompile(`^(?i)(?:in\s+(` + relativeComponent + `(?:\s*` + relativeComponent + `)*)|(` +
+	relativeComponent + `(?:\s*` + relativeComponent + `)*)\s+ago)\b`)
+var relativeComponentPattern = regexp.MustCompile(`(?i)` + relativeComponent)
+
+// relativeUnits are the short units of the spelled out units.
+var relativeUnits = map[string]string{
+	"year": "y", "month": "mo", "week": "w", "day": "d", "hour": "h", "minute": "m", "min": "m", "second": "s",
+	"sec": "s",
+}
+
+func (p relativeTimeStr) Parse(input Cursor) (Node, Cursor, error) {
+	Logf("RelativeTime on: %s$", input)
+	groups := relativePattern.FindStringSubmatch(input.String())
+	if groups == nil {
+		return nil, input, nil
+	}
+	period, ago := groups[1], false
+	if period == "" {
+		period, ago = groups[6], true
+	}
+	// The period is rewritten with the short units and parsed like the other periods.
+	b := strings.Builder{}
+	for _, component := range relativeComponentPattern.FindAllStringSubmatch(period, -1) {
+		unit := strings.ToLower(component[2])
+		if short, ok := relativeUnits[strings.TrimSuffix(unit, "s")]; ok && len(unit) > 2 {
+			unit = short
+		}
+		b.WriteString(component[1] + unit)
+	}
+	node, _, err := Period.Parse(NewCursor(b.String()))
+	if err != nil {
+		return nil, input, err
+	}
+	periodNode := node.(PeriodNode)
+	periodNode.Cur = input
+	return RelativeTimeNode{Period: periodNode, Ago: ago, Cur: input}, input.Advance(len(groups[0])), nil
+}
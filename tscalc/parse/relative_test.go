@@ -0,0 +1,55 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelativeTime(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assert.NoError(t, err)
+	// DST ends on Sunday 2023-10-29.
+	now := time.Date(2023, 10, 28, 12, 0, 0, 0, warsaw)
+	for _, tc := range []struct {
+		input    string
+		expected time.Time
+	}{
+		{"3 hours ago", time.Date(2023, 10, 28, 9, 0, 0, 0, warsaw)},
+		{"2h ago", time.Date(2023, 10, 28, 10, 0, 0, 0, warsaw)},
+		{"1.5h ago", time.Date(2023, 10, 28, 10, 30, 0, 0, warsaw)},
+		{"2h30m AGO", time.Date(2023, 10, 28, 9, 30, 0, 0, warsaw)},
+		{"in 2 days", time.Date(2023, 10, 30, 12, 0, 0, 0, warsaw)},
+		{"in 1 day 2 hours", time.Date(2023, 10, 29, 14, 0, 0, 0, warsaw)},
+		{"in 1 week", time.Date(2023, 11, 4, 12, 0, 0, 0, warsaw)},
+		{"In 5 mins", time.Date(2023, 10, 28, 12, 5, 0, 0, warsaw)},
+		{"1 month ago", time.Date(2023, 9, 28, 12, 0, 0, 0, warsaw)},
+		{"10 seconds ago", time.Date(2023, 10, 28, 11, 59, 50, 0, warsaw)},
+		{"in 24h", time.Date(2023, 10, 29, 11, 0, 0, 0, warsaw)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := RelativeTime.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			actual := node.(RelativeTimeNode).Resolve(now, warsaw).Time
+			assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+		})
+	}
+}
+
+func TestRelativeTimeNoMatch(t *testing.T) {
+	for _, input := range []string{"2h", "in Europe/Warsaw", "2 hours agony", "in 2 parsecs", "ago 2h"} {
+		t.Run(input, func(t *testing.T) {
+			node, rest, err := RelativeTime.Parse(NewCursor(input))
+			assert.NoError(t, err)
+			assert.Nil(t, node)
+			assert.Equal(t, input, rest.String())
+		})
+	}
+}
+
+func TestRelativeTimeFractionalDays(t *testing.T) {
+	_, _, err := RelativeTime.Parse(NewCursor("1.5 days ago"))
+	assert.Error(t, err)
+}
@@ -4,74 +4,138 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
-type PeriodNode struct {
-	Duration time.Duration
-	Cur      Cursor
+// EpochTimeNode is the epoch timestamp. It is kept as integer seconds and nanoseconds, so it is exact up to the
+// nanosecond and can be converted to and from time.Time without loss.
+type EpochTimeNode struct {
+	// sec is the number of seconds since the epoch.
+	sec int64
+	// nsec is the number of nanoseconds within the second, in range [0, 999999999].
+	nsec int64
+	// literal is the number as it was at the input, if the node was parsed.
+	literal string
+	// unit is the unit of the number at the input, if the node was parsed.
+	unit   EpochUnit
+	cursor Cursor
 }
 
-func (n PeriodNode) Cursor() Cursor {
-	return n.Cur
+// Unit returns the unit of the number at the input, or EpochAuto if the node was not parsed.
+func (n EpochTimeNode) Unit() EpochUnit {
+	return n.unit
 }
 
-func (n PeriodNode) String() string {
-	return fmt.Sprint(n.Duration)
+func (n EpochTimeNode) Cursor() Cursor {
+	return n.cursor
 }
 
-type periodStr struct{}
+// isoFormat is used to print the timestamps. The fractional seconds are printed only if they are not zero.
+const isoFormat = "2006-01-02T15:04:05.999999999-07:00"
 
-var Period = periodStr{}
+func (n EpochTimeNode) ToIsoTimeNode() IsoTimeNode {
+	t := time.Unix(n.sec, n.nsec)
+	return IsoTimeNode{Time: t, Cur: n.Cursor()}
+}
 
-func (p periodStr) String() string {
-	return "<period>"
+// Float returns the number as it was at the input, regardless of the unit. It is used when the number is not
+// a timestamp but a plain number, e.g. a factor in multiplication.
+func (n EpochTimeNode) Float() float64 {
+	if f, err := strconv.ParseFloat(n.literal, 64); err == nil {
+		return f
+	}
+	return float64(n.sec) + float64(n.nsec)/1e9
 }
 
-func (p periodStr) Parse(input Cursor) (Node, Cursor, error) {
-	pat := regexp.MustCompile(`^(?:\d+[hms])+`)
-	indices := pat.FindStringSubmatchIndex(input.String())
-	if indices == nil {
-		return nil, input, nil
+// String returns the timestamp in seconds. The fractional part is printed only up to the last significant digit.
+func (n EpochTimeNode) String() string {
+	sign, sec, nsec := "", n.sec, n.nsec
+	if sec < 0 {
+		sign, sec = "-", -sec
+		if nsec > 0 {
+			sec, nsec = sec-1, 1_000_000_000-nsec
+		}
 	}
-	match := input.String()[indices[0]:indices[1]]
-	d, err := time.ParseDuration(match)
-	if err != nil {
-		return nil, input, fmt.Errorf("error while parsing duration %s: %w", d, err)
+	s := sign + strconv.FormatInt(sec, 10)
+	if nsec != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%09d", nsec), "0")
 	}
-	rest := input.Advance(indices[1])
-	return PeriodNode{Duration: d, Cur: input}, rest, nil
+	return s
 }
 
-type EpochTimeNode struct {
-	// ts is epoch timestamp in seconds.
-	ts     float64
-	cursor Cursor
-}
+// EpochUnit is the unit of the epoch timestamp.
+type EpochUnit int
 
-func (n EpochTimeNode) Cursor() Cursor {
-	return n.cursor
-}
+const (
+	// EpochAuto detects the unit from the number of digits.
+	EpochAuto EpochUnit = iota
+	EpochSeconds
+	EpochMillis
+	EpochMicros
+	EpochNanos
+)
 
-const isoFormat = "2006-01-02T15:04:05-07:00"
+var epochUnitNames = map[EpochUnit]string{
+	EpochAuto:    "auto",
+	EpochSeconds: "s",
+	EpochMillis:  "ms",
+	EpochMicros:  "us",
+	EpochNanos:   "ns",
+}
 
-// func (t EpochTimeNode) FormatISO() string {
-// 	sec, frac := math.Modf(float64(t))
-// 	return time.Unix(int64(sec), int64(1_000_000_000*frac)).UTC().Format(isoFormat)
-// }
+func (u EpochUnit) String() string {
+	return epochUnitNames[u]
+}
 
-func (n EpochTimeNode) ToIsoTimeNode() IsoTimeNode {
-	t := time.UnixMicro(int64(1_000_000 * float64(n.ts)))
-	return IsoTimeNode{Time: t, Cur: n.Cursor()}
+// SubSecondDigits returns how many digits of the integer timestamp in this unit are the fraction of a second.
+func (u EpochUnit) SubSecondDigits() int {
+	switch u {
+	case EpochMillis:
+		return 3
+	case EpochMicros:
+		return 6
+	case EpochNanos:
+		return 9
+	}
+	return 0
 }
 
-func (n EpochTimeNode) String() string {
-	return fmt.Sprintf("%f", n.ts)
+// ParseEpochUnit returns the unit for its name: auto, s, ms, us or ns.
+func ParseEpochUnit(name string) (EpochUnit, error) {
+	for u, n := range epochUnitNames {
+		if n == name {
+			return u, nil
+		}
+	}
+	return EpochAuto, fmt.Errorf("unknown epoch unit %q, expected one of auto, s, ms, us, ns", name)
+}
+
+// DetectEpochUnit guesses the unit from the number of digits of the integer part of the timestamp. The current
+// timestamps have 10 digits in seconds, 13 in milliseconds, 16 in microseconds and 19 in nanoseconds.
+func DetectEpochUnit(intDigits int) EpochUnit {
+	switch {
+	case intDigits <= 11:
+		return EpochSeconds
+	case intDigits <= 14:
+		return EpochMillis
+	case intDigits <= 17:
+		return EpochMicros
+	}
+	return EpochNanos
 }
 
+// EpochTime parses the epoch timestamp and detects its unit from the magnitude.
 var EpochTime = epochTimeStr{}
 
-type epochTimeStr struct{}
+// EpochTimeIn parses the epoch timestamp in the given unit. With EpochAuto the unit is detected from the magnitude.
+func EpochTimeIn(unit EpochUnit) Parser {
+	return epochTimeStr{unit: unit}
+}
+
+type epochTimeStr struct {
+	unit EpochUnit
+}
 
 func (t epochTimeStr) String() string {
 	return "<epoch-time>"
@@ -79,22 +143,66 @@ func (t epochTimeStr) String() string {
 
 func (p epochTimeStr) Parse(input Cursor) (Node, Cursor, error) {
 	Logf("EpochTime on: %s$", input)
-	pat := regexp.MustCompile(`^\d+(\.\d+)?`)
-	indices := pat.FindStringIndex(input.String())
+	pat := regexp.MustCompile(`^(\d+)(\.\d+)?`)
+	indices := pat.FindStringSubmatchIndex(input.String())
 	if indices == nil {
 		return nil, input, nil
 	}
-	match := input.String()[indices[0]:indices[1]]
-	t, err := strconv.ParseFloat(match, 64)
+	intPart := input.String()[indices[2]:indices[3]]
+	frac := ""
+	if indices[4] >= 0 {
+		frac = input.String()[indices[4]+1 : indices[5]]
+	}
+	unit := p.unit
+	if unit == EpochAuto {
+		unit = DetectEpochUnit(len(intPart))
+	}
+	Logf("EpochTime unit: %s", unit)
+	literal := input.String()[indices[0]:indices[1]]
+	sec, nsec, err := parseEpochDecimal(intPart, frac, unit)
+	if err != nil {
+		return nil, input, fmt.Errorf("error while parsing %s: %w", literal, err)
+	}
+	return EpochTimeNode{sec: sec, nsec: nsec, literal: literal, unit: unit, cursor: input}, input.Advance(indices[1]), nil
+}
+
+// parseEpochDecimal converts the decimal timestamp in the unit to seconds and nanoseconds. The conversion is done on
+// the digits rather than on floats, so it is exact. The digits beyond nanoseconds are truncated.
+func parseEpochDecimal(intPart, frac string, unit EpochUnit) (int64, int64, error) {
+	digits := intPart + frac
+	// Position of the decimal point of the seconds.
+	point := len(intPart) - unit.SubSecondDigits()
+	if point < 0 {
+		digits = strings.Repeat("0", -point) + digits
+		point = 0
+	}
+	secDigits, nsecDigits := digits[:point], digits[point:]
+	if secDigits == "" {
+		secDigits = "0"
+	}
+	if len(nsecDigits) > 9 {
+		nsecDigits = nsecDigits[:9]
+	} else {
+		nsecDigits += strings.Repeat("0", 9-len(nsecDigits))
+	}
+	sec, err := strconv.ParseInt(secDigits, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	nsec, err := strconv.ParseInt(nsecDigits, 10, 64)
 	if err != nil {
-		return nil, input, fmt.Errorf("error while parsing %s: %w", input, err)
+		return 0, 0, err
 	}
-	return EpochTimeNode{ts: t, cursor: input}, input.Advance(indices[1]), nil
+	return sec, nsec, nil
 }
 
 type IsoTimeNode struct {
 	Time time.Time
-	Cur  Cursor
+	// Loc is the location in which the time is printed. If nil, the time is printed in UTC.
+	Loc *time.Location
+	// Format is the name of the input format the time was parsed from, empty if the time was not parsed.
+	Format string
+	Cur    Cursor
 }
 
 func (n IsoTimeNode) Cursor() Cursor {
@@ -102,12 +210,21 @@ func (n IsoTimeNode) Cursor() Cursor {
 }
 
 func (n IsoTimeNode) String() string {
-	return n.Time.UTC().Format(isoFormat)
+	loc := n.Loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	return n.Time.In(loc).Format(isoFormat)
+}
+
+// In returns the node that is printed in the given location.
+func (n IsoTimeNode) In(loc *time.Location) IsoTimeNode {
+	n.Loc = loc
+	return n
 }
 
 func (n IsoTimeNode) ToEpochTimeNode() EpochTimeNode {
-	ts := n.Time.Unix()
-	return EpochTimeNode{ts: float64(ts), cursor: n.Cursor()}
+	return EpochTimeNode{sec: n.Time.Unix(), nsec: int64(n.Time.Nanosecond()), cursor: n.Cursor()}
 }
 
 type isoTimeStr struct{}
@@ -119,15 +236,16 @@ func (t isoTimeStr) String() string {
 }
 
 func (p isoTimeStr) Parse(input Cursor) (Node, Cursor, error) {
-	pat := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\+\d{2}:\d{2}`)
+	pat := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})`)
 	indices := pat.FindStringIndex(input.String())
 	if indices == nil {
 		return nil, input, nil
 	}
 	match := input.String()[indices[0]:indices[1]]
-	t, err := time.Parse(isoFormat, match)
+	// RFC3339 accepts the fractional seconds when parsing, even though the layout does not have them.
+	t, err := time.Parse(time.RFC3339, match)
 	if err != nil {
-		return nil, input, fmt.Errorf("error while parsing %s: %w", input, err)
+		return nil, input, fmt.Errorf("error while parsing %s: %w", match, err)
 	}
-	return IsoTimeNode{Time: t, Cur: input}, input.Advance(indices[1]), nil
+	return IsoTimeNode{Time: t, Format: "rfc3339", Cur: input}, input.Advance(indices[1]), nil
 }
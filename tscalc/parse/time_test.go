@@ -14,9 +14,105 @@ func TestFormatTime(t *testing.T) {
 		expected string
 	}{
 		{IsoTimeNode{Time: time.Unix(0, 0)}, "1970-01-01T00:00:00+00:00"},
+		{IsoTimeNode{Time: time.Unix(0, 500_000_000)}, "1970-01-01T00:00:00.5+00:00"},
+		{IsoTimeNode{Time: time.Unix(0, 0), Loc: time.FixedZone("", -5*3600)}, "1969-12-31T19:00:00-05:00"},
 	} {
 		t.Run(fmt.Sprintf("%s==%s", tc.t, tc.expected), func(t *testing.T) {
 			assert.Equal(t, tc.expected, fmt.Sprint(tc.t))
 		})
 	}
 }
+
+func TestParseIsoTime(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected time.Time
+	}{
+		{"1970-01-01T00:01:40+00:00", time.Unix(100, 0)},
+		{"1970-01-01T00:01:40Z", time.Unix(100, 0)},
+		{"1970-01-01T00:01:40.25Z", time.Unix(100, 250_000_000)},
+		{"1969-12-31T19:01:40-05:00", time.Unix(100, 0)},
+		{"1970-01-01T02:01:40.000000001+02:00", time.Unix(100, 1)},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := IsoTime.Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			assert.True(t, tc.expected.Equal(node.(IsoTimeNode).Time), "%s", node)
+		})
+	}
+}
+
+func TestDetectEpochUnit(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		unit     EpochUnit
+		expected time.Time
+	}{
+		{"100", EpochAuto, time.Unix(100, 0)},
+		{"1698603564", EpochAuto, time.Unix(1698603564, 0)},
+		{"1698603564000", EpochAuto, time.Unix(1698603564, 0)},
+		{"1698603564000000", EpochAuto, time.Unix(1698603564, 0)},
+		{"1698603564000000000", EpochAuto, time.Unix(1698603564, 0)},
+		{"100", EpochMillis, time.Unix(0, 100_000_000)},
+		{"1698603564", EpochSeconds, time.Unix(1698603564, 0)},
+	} {
+		t.Run(fmt.Sprintf("%s in %s", tc.input, tc.unit), func(t *testing.T) {
+			node, rest, err := EpochTimeIn(tc.unit).Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			actual := node.(EpochTimeNode).ToIsoTimeNode().Time
+			assert.True(t, tc.expected.Equal(actual), "%s != %s", tc.expected, actual)
+		})
+	}
+}
+
+func TestParseEpochUnit(t *testing.T) {
+	unit, err := ParseEpochUnit("ms")
+	assert.NoError(t, err)
+	assert.Equal(t, EpochMillis, unit)
+	_, err = ParseEpochUnit("fortnight")
+	assert.Error(t, err)
+}
+
+func TestEpochTimeRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		unit     EpochUnit
+		expected string
+	}{
+		{"1698603564", EpochAuto, "1698603564"},
+		{"1698603564.000000", EpochAuto, "1698603564"},
+		{"1698603564.123456789", EpochAuto, "1698603564.123456789"},
+		{"1698603564.1234567891", EpochAuto, "1698603564.123456789"},
+		{"1698603564123", EpochAuto, "1698603564.123"},
+		{"1698603564123456789", EpochAuto, "1698603564.123456789"},
+		{"1698603564123.5", EpochAuto, "1698603564.1235"},
+		{"5", EpochNanos, "0.000000005"},
+		{"0.5", EpochAuto, "0.5"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			node, rest, err := EpochTimeIn(tc.unit).Parse(NewCursor(tc.input))
+			assert.NoError(t, err)
+			assert.True(t, rest.Ended(), rest.String())
+			epoch := node.(EpochTimeNode)
+			assert.Equal(t, tc.expected, fmt.Sprint(epoch))
+			assert.Equal(t, tc.expected, fmt.Sprint(epoch.ToIsoTimeNode().ToEpochTimeNode()))
+		})
+	}
+}
+
+func TestFormatNegativeEpochTime(t *testing.T) {
+	for _, tc := range []struct {
+		t        time.Time
+		expected string
+	}{
+		{time.Unix(-1, 0), "-1"},
+		{time.Unix(-1, 500_000_000), "-0.5"},
+		{time.Unix(-2, 250_000_000), "-1.75"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, fmt.Sprint(IsoTimeNode{Time: tc.t}.ToEpochTimeNode()))
+		})
+	}
+}